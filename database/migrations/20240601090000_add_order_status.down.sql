@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS order_status_histories;
+
+DROP INDEX IF EXISTS idx_orders_status CASCADE;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
+
+DROP TYPE IF EXISTS order_status;
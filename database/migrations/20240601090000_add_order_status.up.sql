@@ -0,0 +1,31 @@
+CREATE TYPE order_status
+ AS ENUM (
+'placed',
+'accepted',
+'preparing',
+'picked_up',
+'delivered',
+'cancelled',
+'failed'
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS status order_status NOT NULL DEFAULT 'placed';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+
+CREATE TABLE IF NOT EXISTS order_status_histories (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+order_id UUID NOT NULL REFERENCES orders(id),
+from_status order_status,
+to_status order_status NOT NULL,
+changed_by UUID REFERENCES users(id),
+note VARCHAR,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_histories_order_id ON order_status_histories(order_id);
+
+-- Existing orders start their history as placed
+INSERT INTO order_status_histories (order_id, to_status, created_at)
+SELECT id, 'placed', created_at FROM orders;
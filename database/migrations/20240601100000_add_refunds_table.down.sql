@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS refunds;
+
+DROP INDEX IF EXISTS idx_refunds_status CASCADE;
+
+DROP TYPE IF EXISTS refund_status;
@@ -0,0 +1,21 @@
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+CREATE TYPE refund_status
+ AS ENUM (
+'pending',
+'processed'
+);
+
+CREATE TABLE IF NOT EXISTS refunds (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+order_id UUID UNIQUE NOT NULL REFERENCES orders(id),
+amount INTEGER NOT NULL,
+reason VARCHAR,
+status refund_status NOT NULL DEFAULT 'pending',
+requested_by UUID REFERENCES users(id),
+processed_by UUID REFERENCES users(id),
+processed_at TIMESTAMP WITH TIME ZONE,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_status ON refunds(status);
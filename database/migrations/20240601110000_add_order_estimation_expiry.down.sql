@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_orders_order_estimation_id CASCADE;
+DROP INDEX IF EXISTS idx_order_estimation_user_id CASCADE;
+
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS consumed_at;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,10 @@
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT (CURRENT_TIMESTAMP + INTERVAL '15 minutes');
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS consumed_at TIMESTAMP WITH TIME ZONE;
+
+-- Estimation that already has an order is consumed
+UPDATE order_estimation oe SET consumed_at = o.created_at
+FROM (SELECT order_estimation_id, MIN(created_at) AS created_at FROM orders GROUP BY order_estimation_id) o
+WHERE o.order_estimation_id = oe.id;
+
+CREATE INDEX IF NOT EXISTS idx_order_estimation_user_id ON order_estimation(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_order_estimation_id ON orders(order_estimation_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_order_estimation_items_order_estimation_id CASCADE;
+
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS item_price;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS item_name;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_location_long;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_location_lat;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_name;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_id;
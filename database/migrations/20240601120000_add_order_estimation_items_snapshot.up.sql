@@ -0,0 +1,28 @@
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_id UUID REFERENCES merchants(id);
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_name VARCHAR;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_location_lat REAL;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_location_long REAL;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS item_name VARCHAR;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS item_price INTEGER;
+
+-- Existing estimation uses the current merchant & item data as snapshot
+UPDATE order_estimation_items oei
+SET
+    merchant_id = m.id,
+    merchant_name = m.name,
+    merchant_location_lat = m.location_lat,
+    merchant_location_long = m.location_long,
+    item_name = i.name,
+    item_price = i.price
+FROM items i
+INNER JOIN merchants m ON i.merchant_id = m.id
+WHERE oei.item_id = i.id;
+
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_id SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_name SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_location_lat SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_location_long SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN item_name SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN item_price SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_order_estimation_items_order_estimation_id ON order_estimation_items(order_estimation_id);
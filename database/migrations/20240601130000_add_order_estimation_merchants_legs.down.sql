@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_order_estimation_merchants_order_estimation_id CASCADE;
+
+ALTER TABLE order_estimation_merchants DROP COLUMN IF EXISTS leg_time;
+ALTER TABLE order_estimation_merchants DROP COLUMN IF EXISTS leg_distance;
+ALTER TABLE order_estimation_merchants DROP COLUMN IF EXISTS visit_order;
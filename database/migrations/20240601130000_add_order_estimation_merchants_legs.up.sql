@@ -0,0 +1,5 @@
+ALTER TABLE order_estimation_merchants ADD COLUMN IF NOT EXISTS visit_order INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation_merchants ADD COLUMN IF NOT EXISTS leg_distance REAL NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation_merchants ADD COLUMN IF NOT EXISTS leg_time INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_order_estimation_merchants_order_estimation_id ON order_estimation_merchants(order_estimation_id);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+user_id UUID NOT NULL REFERENCES users(id),
+endpoint VARCHAR NOT NULL,
+key VARCHAR NOT NULL,
+request_hash VARCHAR NOT NULL,
+response_code INTEGER,
+response_body BYTEA,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+PRIMARY KEY (user_id, endpoint, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
@@ -0,0 +1 @@
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS preparation_time INTEGER;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_merchants_active_created_at;
+
+ALTER TABLE merchants DROP COLUMN IF EXISTS deleted_at;
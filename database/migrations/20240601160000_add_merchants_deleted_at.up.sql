@@ -0,0 +1,3 @@
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_merchants_active_created_at ON merchants (created_at) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE items DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE items DROP COLUMN IF EXISTS is_available;
@@ -0,0 +1,6 @@
+drop function if exists is_merchant_open;
+
+DROP TABLE IF EXISTS merchant_closures;
+DROP TABLE IF EXISTS merchant_opening_hours;
+
+ALTER TABLE merchants DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,77 @@
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS timezone VARCHAR NOT NULL DEFAULT 'Asia/Jakarta';
+
+-- Weekly opening hours in merchant timezone.
+-- close_time before open_time means the merchant closes on the next day,
+-- and the same open_time and close_time means the merchant opens all day.
+CREATE TABLE IF NOT EXISTS merchant_opening_hours (
+merchant_id UUID NOT NULL REFERENCES merchants(id),
+day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6), -- 0 is Sunday
+open_time TIME NOT NULL,
+close_time TIME NOT NULL,
+PRIMARY KEY (merchant_id, day_of_week)
+);
+
+-- One-off closure, e.g. holiday or renovation
+CREATE TABLE IF NOT EXISTS merchant_closures (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+merchant_id UUID NOT NULL REFERENCES merchants(id),
+start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+end_at TIMESTAMP WITH TIME ZONE NOT NULL CHECK (end_at > start_at),
+reason VARCHAR,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_closures_merchant_id_end_at ON merchant_closures(merchant_id, end_at);
+
+-- Merchant without opening hours is always open unless it has a closure
+create
+or replace function is_merchant_open(
+    p_merchant_id uuid,
+    p_at timestamp with time zone
+) returns boolean as
+$$
+declare
+    tz varchar;
+    local_at timestamp;
+    local_day integer;
+    local_time time;
+begin
+if exists (
+    select 1 from merchant_closures c
+    where c.merchant_id = p_merchant_id and p_at >= c.start_at and p_at < c.end_at
+) then
+    return false;
+end if;
+
+if not exists (
+    select 1 from merchant_opening_hours h where h.merchant_id = p_merchant_id
+) then
+    return true;
+end if;
+
+select m.timezone into tz from merchants m where m.id = p_merchant_id;
+
+local_at := p_at at time zone tz;
+
+local_day := extract(dow from local_at);
+
+local_time := local_at::time;
+
+return exists (
+    select 1 from merchant_opening_hours h
+    where h.merchant_id = p_merchant_id and (
+        (
+            h.day_of_week = local_day and (
+                h.open_time = h.close_time
+                or (h.open_time < h.close_time and local_time >= h.open_time and local_time < h.close_time)
+                or (h.open_time > h.close_time and local_time >= h.open_time)
+            )
+        )
+        -- Opening hours of yesterday that pass midnight
+        or (h.day_of_week = (local_day + 6) % 7 and h.open_time > h.close_time and local_time < h.close_time)
+    )
+);
+
+end;
+
+$$ language plpgsql stable;
@@ -0,0 +1,5 @@
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS options_price;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS options;
+
+DROP TABLE IF EXISTS item_options;
+DROP TABLE IF EXISTS item_option_groups;
@@ -0,0 +1,30 @@
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+-- Option group of an item, e.g. size, spice level or extra toppings
+CREATE TABLE IF NOT EXISTS item_option_groups (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+item_id UUID NOT NULL REFERENCES items(id),
+name VARCHAR NOT NULL,
+min_select INTEGER NOT NULL DEFAULT 0,
+max_select INTEGER NOT NULL DEFAULT 1,
+position INTEGER NOT NULL DEFAULT 0,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+CHECK (min_select >= 0 AND max_select >= min_select AND max_select > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_option_groups_item_id ON item_option_groups(item_id);
+
+CREATE TABLE IF NOT EXISTS item_options (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+option_group_id UUID NOT NULL REFERENCES item_option_groups(id) ON DELETE CASCADE,
+name VARCHAR NOT NULL,
+price_delta INTEGER NOT NULL DEFAULT 0,
+position INTEGER NOT NULL DEFAULT 0,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_options_option_group_id ON item_options(option_group_id);
+
+-- Snapshot of selected options of the ordered item
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS options_price INTEGER NOT NULL DEFAULT 0;
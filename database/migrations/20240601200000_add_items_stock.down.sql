@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS stock_reservations;
+
+DROP TYPE IF EXISTS stock_reservation_status;
+
+ALTER TABLE items DROP COLUMN IF EXISTS stock;
@@ -0,0 +1,22 @@
+-- Quantity available to order, reserved quantity is already subtracted.
+-- NULL means the stock of the item is not tracked.
+ALTER TABLE items ADD COLUMN IF NOT EXISTS stock INTEGER CHECK (stock >= 0);
+
+CREATE TYPE stock_reservation_status
+ AS ENUM (
+'reserved',
+'committed',
+'released'
+);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+order_estimation_id UUID NOT NULL REFERENCES order_estimation(id),
+item_id UUID NOT NULL REFERENCES items(id),
+quantity INTEGER NOT NULL CHECK (quantity > 0),
+status stock_reservation_status NOT NULL DEFAULT 'reserved',
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+PRIMARY KEY (order_estimation_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_reserved ON stock_reservations(order_estimation_id) WHERE status = 'reserved';
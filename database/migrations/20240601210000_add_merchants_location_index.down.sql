@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_merchants_active_location;
@@ -0,0 +1,2 @@
+-- Nearby search pre-filters merchants with a bounding box of maxDistance before the exact distance is calculated
+CREATE INDEX IF NOT EXISTS idx_merchants_active_location ON merchants (location_lat, location_long) WHERE deleted_at IS NULL;
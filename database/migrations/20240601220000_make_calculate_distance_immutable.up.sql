@@ -0,0 +1,14 @@
+-- Same haversine formula as distances.Calculate, square root is capped to 1 so rounding error of nearly antipodal points does not fail ASIN.
+-- IMMUTABLE lets the planner fold constant input and allows the function in index expression.
+CREATE OR REPLACE FUNCTION calculate_distance(
+    lat1 float,
+    long1 float,
+    lat2 float,
+    long2 float
+) RETURNS float AS
+$$
+SELECT 6371.009 * 2 * ASIN(LEAST(1, SQRT(
+    POWER(SIN(RADIANS(lat2 - lat1) / 2), 2)
+    + COS(RADIANS(lat1)) * COS(RADIANS(lat2)) * POWER(SIN(RADIANS(long2 - long1) / 2), 2)
+)));
+$$ LANGUAGE sql IMMUTABLE STRICT PARALLEL SAFE;
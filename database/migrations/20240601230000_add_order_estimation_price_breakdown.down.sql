@@ -0,0 +1,7 @@
+ALTER TABLE order_estimation_merchants DROP COLUMN IF EXISTS small_order_fee;
+
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS tax;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS service_fee;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS small_order_fee;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS delivery_fee;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS items_price;
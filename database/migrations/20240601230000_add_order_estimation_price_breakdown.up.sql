@@ -0,0 +1,11 @@
+-- total_price is the sum of items price, every fee and tax
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS items_price INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS delivery_fee INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS small_order_fee INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS service_fee INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS tax INTEGER NOT NULL DEFAULT 0;
+
+-- Existing estimation only has items price
+UPDATE order_estimation SET items_price = total_price;
+
+ALTER TABLE order_estimation_merchants ADD COLUMN IF NOT EXISTS small_order_fee INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_order_estimation_promotion_id;
+
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS discount;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS voucher_code;
+ALTER TABLE order_estimation DROP COLUMN IF EXISTS promotion_id;
+
+DROP TABLE IF EXISTS promotions;
+
+DROP TYPE IF EXISTS promotion_discount_type;
@@ -0,0 +1,35 @@
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+CREATE TYPE promotion_discount_type
+ AS ENUM (
+'percentage',
+'fixed',
+'free_delivery'
+);
+
+CREATE TABLE IF NOT EXISTS promotions (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+code VARCHAR(30) NOT NULL,
+description VARCHAR(200) NOT NULL DEFAULT '',
+discount_type promotion_discount_type NOT NULL,
+discount_value INTEGER NOT NULL DEFAULT 0, -- percent for percentage discount, amount for fixed discount
+max_discount INTEGER, -- cap of percentage discount
+min_spend INTEGER NOT NULL DEFAULT 0,
+merchant_id UUID REFERENCES merchants(id),
+merchant_category merchant_categories,
+usage_limit INTEGER,
+usage_limit_per_user INTEGER,
+starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+ends_at TIMESTAMP WITH TIME ZONE,
+deleted_at TIMESTAMP WITH TIME ZONE,
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Code is case insensitive and can be reused after the promotion is deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_active_code ON promotions (UPPER(code)) WHERE deleted_at IS NULL;
+
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS promotion_id UUID REFERENCES promotions(id);
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS voucher_code VARCHAR(30);
+ALTER TABLE order_estimation ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_order_estimation_promotion_id ON order_estimation(promotion_id) WHERE promotion_id IS NOT NULL;
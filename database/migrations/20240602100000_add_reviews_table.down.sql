@@ -0,0 +1,8 @@
+ALTER TABLE items DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE items DROP COLUMN IF EXISTS rating_average;
+ALTER TABLE merchants DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE merchants DROP COLUMN IF EXISTS rating_average;
+
+DROP TABLE IF EXISTS item_reviews;
+
+DROP TABLE IF EXISTS merchant_reviews;
@@ -0,0 +1,29 @@
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+-- Review of a merchant in a delivered order, one per merchant per order
+CREATE TABLE IF NOT EXISTS merchant_reviews (
+id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+order_id UUID NOT NULL REFERENCES orders(id),
+merchant_id UUID NOT NULL REFERENCES merchants(id),
+user_id UUID NOT NULL REFERENCES users(id),
+rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+comment VARCHAR(500) NOT NULL DEFAULT '',
+created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+UNIQUE (order_id, merchant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_reviews_merchant_id_created_at ON merchant_reviews(merchant_id, created_at DESC);
+
+-- Optional rating of the ordered items of the reviewed merchant
+CREATE TABLE IF NOT EXISTS item_reviews (
+review_id UUID NOT NULL REFERENCES merchant_reviews(id) ON DELETE CASCADE,
+item_id UUID NOT NULL REFERENCES items(id),
+rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+PRIMARY KEY (review_id, item_id)
+);
+
+-- Aggregate is updated together with the review, so listing doesn't need to scan reviews
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS rating_average REAL NOT NULL DEFAULT 0;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS rating_average REAL NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS item_created_at;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS item_image_url;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS item_product_category;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_created_at;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_image_url;
+ALTER TABLE order_estimation_items DROP COLUMN IF EXISTS merchant_category;
@@ -0,0 +1,26 @@
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_category merchant_categories;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_image_url VARCHAR;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS merchant_created_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS item_product_category product_categories;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS item_image_url VARCHAR;
+ALTER TABLE order_estimation_items ADD COLUMN IF NOT EXISTS item_created_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing estimation uses the current merchant & item data as snapshot
+UPDATE order_estimation_items oei
+SET
+    merchant_category = m.merchant_category,
+    merchant_image_url = m.image_url,
+    merchant_created_at = m.created_at,
+    item_product_category = i.product_category,
+    item_image_url = i.image_url,
+    item_created_at = i.created_at
+FROM items i
+INNER JOIN merchants m ON i.merchant_id = m.id
+WHERE oei.item_id = i.id;
+
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_category SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_image_url SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN merchant_created_at SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN item_product_category SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN item_image_url SET NOT NULL;
+ALTER TABLE order_estimation_items ALTER COLUMN item_created_at SET NOT NULL;
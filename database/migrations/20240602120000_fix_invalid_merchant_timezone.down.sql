@@ -0,0 +1 @@
+-- Invalid timezone is not restored
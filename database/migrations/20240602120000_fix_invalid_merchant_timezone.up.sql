@@ -0,0 +1,4 @@
+-- Timezone that postgres doesn't know fails AT TIME ZONE of is_merchant_open, use the default timezone instead
+UPDATE merchants
+SET timezone = 'Asia/Jakarta'
+WHERE timezone NOT IN (SELECT name FROM pg_timezone_names);
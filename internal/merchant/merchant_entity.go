@@ -1,6 +1,7 @@
 package merchant
 
 import (
+	"database/sql"
 	"time"
 	// "log"
 )
@@ -16,6 +17,16 @@ const (
 	ConvenienceStore      MerchantCategories = "ConvenienceStore"
 )
 
+// Check if the category is one of the merchant categories
+func (c MerchantCategories) IsValid() bool {
+	switch c {
+	case SmallRestaurant, MediumRestaurant, LargeRestaurant, MerchandiseRestaurant, BoothKiosk, ConvenienceStore:
+		return true
+	}
+
+	return false
+}
+
 type Merchant struct {
 	ID               string             `json:"id" db:"id"`
 	Name             string             `json:"name" db:"name"`
@@ -23,7 +34,12 @@ type Merchant struct {
 	ImageUrl         string             `json:"imageUrl" db:"image_url"`
 	LocationLat      float64            `json:"locationLat" db:"location_lat"`
 	LocationLong     float64            `json:"locationLong" db:"location_long"`
+	PreparationTime  sql.NullInt32      `json:"-" db:"preparation_time"` // In minutes
 	CreatedAt        time.Time          `json:"createdAt" db:"created_at"`
+	DeletedAt        sql.NullTime       `json:"-" db:"deleted_at"`
+	Timezone         string             `json:"timezone" db:"timezone"`
+	RatingAverage    float64            `json:"rating" db:"rating_average"`
+	RatingCount      int                `json:"reviewCount" db:"rating_count"`
 }
 
 type Location struct {
@@ -36,6 +52,17 @@ type CreateMerchantDTO struct {
 	MerchantCategory MerchantCategories `json:"merchantCategory" binding:"required,oneof=SmallRestaurant MediumRestaurant LargeRestaurant MerchandiseRestaurant BoothKiosk ConvenienceStore"`
 	ImageUrl         string             `json:"imageUrl" binding:"required,url,contains=."`
 	Location         Location           `json:"location" binding:"required"`
+	PreparationTime  *int               `json:"preparationTimeInMinutes" binding:"omitempty,min=0,max=240"`
+	Timezone         string             `json:"timezone" binding:"omitempty,timezone"` // Default to Asia/Jakarta
+}
+
+// Every field is optional, only given field is updated
+type UpdateMerchantDTO struct {
+	Name             *string             `json:"name" binding:"omitempty,min=2,max=30"`
+	MerchantCategory *MerchantCategories `json:"merchantCategory" binding:"omitempty,oneof=SmallRestaurant MediumRestaurant LargeRestaurant MerchandiseRestaurant BoothKiosk ConvenienceStore"`
+	ImageUrl         *string             `json:"imageUrl" binding:"omitempty,url,contains=."`
+	Location         *Location           `json:"location"`
+	PreparationTime  *int                `json:"preparationTimeInMinutes" binding:"omitempty,min=0,max=240"`
 }
 
 type CreateMerchantResponse struct {
@@ -59,7 +86,12 @@ type Item struct {
 	ProductCategory ProductCategories `json:"productCategory" db:"product_category"`
 	Price           int               `json:"price" db:"price"`
 	ImageUrl        string            `json:"imageUrl" db:"image_url"`
+	IsAvailable     bool              `json:"isAvailable" db:"is_available"`
+	Stock           sql.NullInt32     `json:"-" db:"stock"` // Quantity available to order, null means not tracked
 	CreatedAt       time.Time         `json:"createdAt" db:"created_at"`
+	DeletedAt       sql.NullTime      `json:"-" db:"deleted_at"`
+	RatingAverage   float64           `json:"rating" db:"rating_average"`
+	RatingCount     int               `json:"reviewCount" db:"rating_count"`
 }
 
 type CreateItemDTO struct {
@@ -67,6 +99,72 @@ type CreateItemDTO struct {
 	ProductCategory ProductCategories `json:"productCategory" binding:"required,oneof=Beverage Food Snack Condiments Additions"`
 	Price           int               `json:"price" binding:"required,min=1"`
 	ImageUrl        string            `json:"imageUrl" binding:"required,url,contains=."`
+	IsAvailable     *bool             `json:"isAvailable"`                     // Default to true
+	Stock           *int              `json:"stock" binding:"omitempty,min=0"` // Empty means stock is not tracked
+}
+
+// Option group of an item, e.g. size, spice level or extra toppings.
+// User has to select between MinSelect and MaxSelect options of the group.
+type OptionGroup struct {
+	ID        string    `db:"id"`
+	ItemID    string    `db:"item_id"`
+	Name      string    `db:"name"`
+	MinSelect int       `db:"min_select"`
+	MaxSelect int       `db:"max_select"`
+	Position  int       `db:"position"` // Display order in the item
+	CreatedAt time.Time `db:"created_at"`
+	Options   []Option  `db:"-"`
+}
+
+type Option struct {
+	ID            string    `db:"id"`
+	OptionGroupID string    `db:"option_group_id"`
+	Name          string    `db:"name"`
+	PriceDelta    int       `db:"price_delta"` // Added to the item price
+	Position      int       `db:"position"`    // Display order in the group
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+type OptionDTO struct {
+	Name       string `json:"name" binding:"required,min=1,max=30"`
+	PriceDelta *int   `json:"priceDelta" binding:"required,min=0"`
+}
+
+type OptionGroupDTO struct {
+	Name      string      `json:"name" binding:"required,min=1,max=30"`
+	MinSelect *int        `json:"minSelect" binding:"required,min=0"`
+	MaxSelect int         `json:"maxSelect" binding:"required,min=1,gtefield=MinSelect"`
+	Options   []OptionDTO `json:"options" binding:"required,min=1,dive"`
+}
+
+// Replace every option group of the item
+type UpdateOptionGroupsDTO struct {
+	OptionGroups []OptionGroupDTO `json:"optionGroups" binding:"dive"`
+}
+
+type OptionResponse struct {
+	OptionID   string `json:"optionId"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"priceDelta"`
+}
+
+type OptionGroupResponse struct {
+	OptionGroupID string           `json:"optionGroupId"`
+	Name          string           `json:"name"`
+	MinSelect     int              `json:"minSelect"`
+	MaxSelect     int              `json:"maxSelect"`
+	Options       []OptionResponse `json:"options"`
+}
+
+// Every field is optional, only given field is updated
+type UpdateItemDTO struct {
+	Name            *string            `json:"name" binding:"omitempty,min=2,max=30"`
+	ProductCategory *ProductCategories `json:"productCategory" binding:"omitempty,oneof=Beverage Food Snack Condiments Additions"`
+	Price           *int               `json:"price" binding:"omitempty,min=1"`
+	ImageUrl        *string            `json:"imageUrl" binding:"omitempty,url,contains=."`
+	IsAvailable     *bool              `json:"isAvailable"`
+	Stock           *int               `json:"stock" binding:"omitempty,min=0"`
+	TrackStock      *bool              `json:"trackStock"` // False stops tracking the stock of the item
 }
 
 type GetItemQueryParam struct {
@@ -76,15 +174,21 @@ type GetItemQueryParam struct {
 	Name            string            `form:"name"`
 	ProductCategory ProductCategories `form:"productCategory"`
 	CreatedAt       Sort              `form:"createdAt"`
+	Cursor          string            `form:"cursor"` // Replace offset with the nextCursor of the previous page
 }
 
 type ItemResponse struct {
-	ID              string            `json:"itemId"`
-	Name            string            `json:"name"`
-	ProductCategory ProductCategories `json:"productCategory"`
-	Price           int               `json:"price"`
-	ImageUrl        string            `json:"imageUrl"`
-	CreatedAt       string            `json:"createdAt"`
+	ID              string                `json:"itemId"`
+	Name            string                `json:"name"`
+	ProductCategory ProductCategories     `json:"productCategory"`
+	Price           int                   `json:"price"`
+	ImageUrl        string                `json:"imageUrl"`
+	IsAvailable     bool                  `json:"isAvailable"`
+	Stock           *int                  `json:"stock"`
+	Rating          float64               `json:"rating"`
+	ReviewCount     int                   `json:"reviewCount"`
+	OptionGroups    []OptionGroupResponse `json:"optionGroups"`
+	CreatedAt       string                `json:"createdAt"`
 }
 
 type CreateItemResponse struct {
@@ -105,6 +209,11 @@ type GetMerchantQueryParams struct {
 	Name             string             `form:"name"`
 	MerchantCategory MerchantCategories `form:"merchantCategory"`
 	CreatedAt        Sort               `form:"createdAt"`
+	OpenNow          bool               `form:"openNow"`
+	Cursor           string             `form:"cursor"`                                           // Replace offset with the nextCursor of the previous page, rejected by nearby merchants
+	MaxDistance      float64            `form:"maxDistance" binding:"omitempty,gt=0"`             // Maximum distance in km of nearby merchants
+	SortBy           string             `form:"sortBy" binding:"omitempty,oneof=distance rating"` // Sort of nearby merchants, default to distance
+	MinRating        float64            `form:"minRating" binding:"omitempty,min=1,max=5"`
 }
 
 type GetMerchantResponse struct {
@@ -113,16 +222,20 @@ type GetMerchantResponse struct {
 	MerchantCategory MerchantCategories `json:"merchantCategory"`
 	ImageUrl         string             `json:"imageUrl"`
 	Location         Location           `json:"location"`
+	Timezone         string             `json:"timezone"`
+	Rating           float64            `json:"rating"`
+	ReviewCount      int                `json:"reviewCount"`
 	CreatedAt        string             `json:"createdAt"`
 }
 
 type ItemForNearbyMerchant struct {
-	ID              string            `json:"itemId"`
-	Name            string            `json:"name"`
-	ProductCategory ProductCategories `json:"productCategory"`
-	Price           int               `json:"price"`
-	ImageUrl        string            `json:"imageUrl"`
-	CreatedAt       time.Time         `json:"createdAt"`
+	ID              string                `json:"itemId"`
+	Name            string                `json:"name"`
+	ProductCategory ProductCategories     `json:"productCategory"`
+	Price           int                   `json:"price"`
+	ImageUrl        string                `json:"imageUrl"`
+	OptionGroups    []OptionGroupResponse `json:"optionGroups"`
+	CreatedAt       time.Time             `json:"createdAt"`
 }
 
 type NearbyMerchantWithItem struct {
@@ -131,6 +244,9 @@ type NearbyMerchantWithItem struct {
 	MerchantCategory MerchantCategories `json:"merchantCategory"`
 	ImageUrl         string             `json:"imageUrl"`
 	Location         Location           `json:"location"`
+	Distance         float64            `json:"distance"` // Distance in km from the user location
+	Rating           float64            `json:"rating"`
+	ReviewCount      int                `json:"reviewCount"`
 	CreatedAt        time.Time          `json:"createdAt"`
 	// Items				[]ItemForNearbyMerchant `json:"items"`
 }
@@ -150,6 +266,8 @@ type MerchantWithItemQueryResult struct {
 	ItemImageUrl      string             `json:"itemImageUrl" db:"item_image_url"`
 	ItemCreatedAt     time.Time          `json:"itemCreatedAt" db:"item_created_at"`
 	Distance          float64            `json:"distance" db:"distance"`
+	RatingAverage     float64            `json:"rating" db:"rating_average"`
+	RatingCount       int                `json:"reviewCount" db:"rating_count"`
 }
 
 type NearbyMerchantWithItemResponse struct {
@@ -170,7 +288,10 @@ func FormatGetMerchantResponse(merchants []Merchant) []GetMerchantResponse {
 				Lat:  merchant.LocationLat,
 				Long: merchant.LocationLong,
 			},
-			CreatedAt: merchant.CreatedAt.Format(time.RFC3339),
+			Timezone:    merchant.Timezone,
+			Rating:      merchant.RatingAverage,
+			ReviewCount: merchant.RatingCount,
+			CreatedAt:   merchant.CreatedAt.Format(time.RFC3339),
 		}
 		getMerchantResponse = append(getMerchantResponse, row)
 	}
@@ -188,9 +309,18 @@ func FormatItemResponse(items []Item) []ItemResponse {
 			ProductCategory: item.ProductCategory,
 			Price:           item.Price,
 			ImageUrl:        item.ImageUrl,
+			IsAvailable:     item.IsAvailable,
+			Rating:          item.RatingAverage,
+			ReviewCount:     item.RatingCount,
+			OptionGroups:    []OptionGroupResponse{},
 			CreatedAt:       item.CreatedAt.Format(time.RFC3339),
 		}
 
+		if item.Stock.Valid {
+			stock := int(item.Stock.Int32)
+			row.Stock = &stock
+		}
+
 		itemsResponse = append(itemsResponse, row)
 	}
 
@@ -217,6 +347,9 @@ func FormatNearbyMerchantWithItemResponse(merchants []MerchantWithItemQueryResul
 			Name:             m.MerchantName,
 			MerchantCategory: m.MerchantCategory,
 			ImageUrl:         m.MerchantImageUrl,
+			Distance:         m.Distance,
+			Rating:           m.RatingAverage,
+			ReviewCount:      m.RatingCount,
 			CreatedAt:        m.MerchantCreatedAt,
 		}
 		item = ItemForNearbyMerchant{
@@ -225,6 +358,7 @@ func FormatNearbyMerchantWithItemResponse(merchants []MerchantWithItemQueryResul
 			ProductCategory: m.ProductCategory,
 			Price:           m.Price,
 			ImageUrl:        m.ItemImageUrl,
+			OptionGroups:    []OptionGroupResponse{},
 			CreatedAt:       m.ItemCreatedAt,
 		}
 		items = append(items, item)
@@ -249,3 +383,28 @@ func FormatNearbyMerchantWithItemResponse(merchants []MerchantWithItemQueryResul
 
 	return res
 }
+
+func FormatOptionGroupResponse(groups []OptionGroup) []OptionGroupResponse {
+	response := []OptionGroupResponse{}
+
+	for _, group := range groups {
+		options := []OptionResponse{}
+		for _, option := range group.Options {
+			options = append(options, OptionResponse{
+				OptionID:   option.ID,
+				Name:       option.Name,
+				PriceDelta: option.PriceDelta,
+			})
+		}
+
+		response = append(response, OptionGroupResponse{
+			OptionGroupID: group.ID,
+			Name:          group.Name,
+			MinSelect:     group.MinSelect,
+			MaxSelect:     group.MaxSelect,
+			Options:       options,
+		})
+	}
+
+	return response
+}
@@ -30,9 +30,26 @@ func (h *merchantHandler) Router(r *gin.RouterGroup) {
 	adminGroup := r.Group("admin/merchants", middleware.UseJwtAuth, middleware.HasRoles(string(user.ADMIN)))
 	userGroup := r.Group("", middleware.UseJwtAuth, middleware.HasRoles(string(user.USER)))
 
+	// Routes of a single merchant, merchant ID should be a UUID
+	merchantGroup := adminGroup.Group("/:merchantId", middleware.ValidateUUIDParam("merchantId", "Merchant data not found"))
+
 	adminGroup.POST("", h.CreateMerchant)
-	adminGroup.POST("/:merchantId/items", h.CreateItem)
-	adminGroup.GET("/:merchantId/items", h.FindItemByMerchant)
+	merchantGroup.PATCH("", h.UpdateMerchant)
+	merchantGroup.DELETE("", h.DeleteMerchant)
+	merchantGroup.POST("/items", h.CreateItem)
+	merchantGroup.GET("/items", h.FindItemByMerchant)
+	// Routes of a single item of the merchant, item ID should be a UUID
+	itemGroup := merchantGroup.Group("/items/:itemId", middleware.ValidateUUIDParam("itemId", "Item data not found"))
+
+	itemGroup.PATCH("", h.UpdateItem)
+	itemGroup.DELETE("", h.DeleteItem)
+	itemGroup.GET("/option-groups", h.FindItemOptionGroups)
+	itemGroup.PUT("/option-groups", h.UpdateItemOptionGroups)
+	merchantGroup.GET("/opening-hours", h.FindOpeningHours)
+	merchantGroup.PUT("/opening-hours", h.UpdateOpeningHours)
+	merchantGroup.GET("/closures", h.FindClosures)
+	merchantGroup.POST("/closures", h.CreateClosure)
+	merchantGroup.DELETE("/closures/:closureId", middleware.ValidateUUIDParam("closureId", "Closure data not found"), h.DeleteClosure)
 	adminGroup.GET("", h.FindAllMerchants)
 
 	userGroup.GET("/merchants/nearby/:latlong", h.GetLatLong, h.FindNearbyMerchants)
@@ -68,6 +85,39 @@ func (h *merchantHandler) CreateMerchant(ctx *gin.Context) {
 	response.GenerateResponseReturnData(ctx, 201, response.WithData(*resp))
 }
 
+func (h *merchantHandler) UpdateMerchant(ctx *gin.Context) {
+	var request UpdateMerchantDTO
+	merchantId := ctx.Param("merchantId")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(ctx, res.Code, response.WithMessage(res.Message))
+		ctx.Abort()
+		return
+	}
+
+	resp, respError := h.uc.UpdateMerchant(merchantId, request)
+	if respError != nil {
+		response.GenerateResponse(ctx, respError.Code, response.WithMessage(respError.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(ctx, http.StatusOK, response.WithData(*resp))
+}
+
+func (h *merchantHandler) DeleteMerchant(ctx *gin.Context) {
+	merchantId := ctx.Param("merchantId")
+
+	if err := h.uc.DeleteMerchant(merchantId); err != nil {
+		response.GenerateResponse(ctx, err.Code, response.WithMessage(err.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponse(ctx, http.StatusOK, response.WithMessage("Merchant deleted successfully!"))
+}
+
 func (h *merchantHandler) CreateItem(ctx *gin.Context) {
 	var request CreateItemDTO
 	merchantId := ctx.Param("merchantId")
@@ -116,6 +166,77 @@ func (h *merchantHandler) FindAllMerchants(c *gin.Context) {
 	response.GenerateResponseReturnData(c, http.StatusOK, response.WithMessage("Product fetched successfully!"), response.WithData(merchants))
 }
 
+func (h *merchantHandler) UpdateItem(ctx *gin.Context) {
+	var request UpdateItemDTO
+	merchantId := ctx.Param("merchantId")
+	itemId := ctx.Param("itemId")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(ctx, res.Code, response.WithMessage(res.Message))
+		ctx.Abort()
+		return
+	}
+
+	resp, respError := h.uc.UpdateItem(merchantId, itemId, request)
+	if respError != nil {
+		response.GenerateResponse(ctx, respError.Code, response.WithMessage(respError.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(ctx, http.StatusOK, response.WithData(*resp))
+}
+
+func (h *merchantHandler) DeleteItem(ctx *gin.Context) {
+	merchantId := ctx.Param("merchantId")
+	itemId := ctx.Param("itemId")
+
+	if err := h.uc.DeleteItem(merchantId, itemId); err != nil {
+		response.GenerateResponse(ctx, err.Code, response.WithMessage(err.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponse(ctx, http.StatusOK, response.WithMessage("Item deleted successfully!"))
+}
+
+func (h *merchantHandler) FindItemOptionGroups(ctx *gin.Context) {
+	merchantId := ctx.Param("merchantId")
+	itemId := ctx.Param("itemId")
+
+	resp, err := h.uc.FindItemOptionGroups(merchantId, itemId)
+	if err != nil {
+		response.GenerateResponse(ctx, err.Code, response.WithMessage(err.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(ctx, http.StatusOK, response.WithData(resp))
+}
+
+func (h *merchantHandler) UpdateItemOptionGroups(ctx *gin.Context) {
+	var request UpdateOptionGroupsDTO
+	merchantId := ctx.Param("merchantId")
+	itemId := ctx.Param("itemId")
+
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(ctx, res.Code, response.WithMessage(res.Message))
+		ctx.Abort()
+		return
+	}
+
+	resp, err := h.uc.UpdateItemOptionGroups(merchantId, itemId, request)
+	if err != nil {
+		response.GenerateResponse(ctx, err.Code, response.WithMessage(err.Message))
+		ctx.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(ctx, http.StatusOK, response.WithData(resp))
+}
+
 func (h *merchantHandler) FindItemByMerchant(c *gin.Context) {
 	var query GetItemQueryParam
 
@@ -137,6 +258,87 @@ func (h *merchantHandler) FindItemByMerchant(c *gin.Context) {
 	response.GenerateResponseReturnData(c, http.StatusOK, response.WithData(items))
 }
 
+func (h *merchantHandler) FindOpeningHours(c *gin.Context) {
+	merchantId := c.Param("merchantId")
+
+	resp, err := h.uc.FindOpeningHours(merchantId)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusOK, response.WithData(*resp))
+}
+
+func (h *merchantHandler) UpdateOpeningHours(c *gin.Context) {
+	var request UpdateOpeningHoursDTO
+	merchantId := c.Param("merchantId")
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	resp, err := h.uc.UpdateOpeningHours(merchantId, request)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusOK, response.WithData(*resp))
+}
+
+func (h *merchantHandler) FindClosures(c *gin.Context) {
+	merchantId := c.Param("merchantId")
+
+	resp, err := h.uc.FindClosures(merchantId)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusOK, response.WithData(resp))
+}
+
+func (h *merchantHandler) CreateClosure(c *gin.Context) {
+	var request CreateClosureDTO
+	merchantId := c.Param("merchantId")
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	resp, err := h.uc.CreateClosure(merchantId, request)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusCreated, response.WithData(*resp))
+}
+
+func (h *merchantHandler) DeleteClosure(c *gin.Context) {
+	merchantId := c.Param("merchantId")
+	closureId := c.Param("closureId")
+
+	if err := h.uc.DeleteClosure(merchantId, closureId); err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponse(c, http.StatusOK, response.WithMessage("Closure deleted successfully!"))
+}
+
 func (h *merchantHandler) GetLatLong(ctx *gin.Context) {
 	latlong := ctx.Param("latlong")
 	latlongArr := strings.Split(latlong, ",")
@@ -2,26 +2,34 @@ package merchant
 
 import (
 	localError "belimang/pkg/error"
+	"belimang/pkg/distances"
+	"belimang/pkg/sqlbuilder"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
-
-	// "strings"
+	"strings"
 
 	// "github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type IMerchantRepository interface {
-	FindAllMerchants(params GetMerchantQueryParams) ([]Merchant, *localError.GlobalError)
+	FindAllMerchants(params GetMerchantQueryParams) ([]Merchant, int, *localError.GlobalError)
 	FindMerchantById(merchantId string) (*Merchant, *localError.GlobalError)
 	CreateMerchant(entity Merchant) *localError.GlobalError
-	FindAllItem(params GetItemQueryParam, merchantId string) ([]Item, *localError.GlobalError)
+	UpdateMerchant(entity Merchant) *localError.GlobalError
+	DeleteMerchant(merchantId string) *localError.GlobalError
+	FindAllItem(params GetItemQueryParam, merchantId string) ([]Item, int, *localError.GlobalError)
 	CreateItem(entity Item) *localError.GlobalError
+	FindItemById(merchantId string, itemId string) (*Item, *localError.GlobalError)
+	UpdateItem(entity Item, stockChanged bool) *localError.GlobalError
+	DeleteItem(merchantId string, itemId string) *localError.GlobalError
+	FindOptionGroups(itemIDs []string) ([]OptionGroup, *localError.GlobalError)
+	ReplaceOptionGroups(itemId string, groups []OptionGroup) *localError.GlobalError
 	CheckMerchantIDs(IDs []string) ([]Merchant, *localError.GlobalError)
 	CheckItemIDs(IDs []string) ([]Item, *localError.GlobalError)
-	FindNearbyMerchants(location Location, params GetMerchantQueryParams) ([]MerchantWithItemQueryResult, *localError.GlobalError)
+	FindNearbyMerchants(location Location, params GetMerchantQueryParams) ([]MerchantWithItemQueryResult, int, *localError.GlobalError)
 }
 
 type merchantRepository struct {
@@ -38,7 +46,7 @@ func NewMerchantRepository(db *sqlx.DB) IMerchantRepository {
 func (u *merchantRepository) FindMerchantById(merchantId string) (*Merchant, *localError.GlobalError) {
 	merchant := Merchant{}
 
-	if err := u.db.Get(&merchant, "SELECT * FROM merchants where id=$1", merchantId); err != nil {
+	if err := u.db.Get(&merchant, "SELECT * FROM merchants where id=$1 AND deleted_at IS NULL", merchantId); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, localError.ErrNotFound("Merchant data not found", err)
 		}
@@ -58,7 +66,7 @@ func (u *merchantRepository) FindMerchantById(merchantId string) (*Merchant, *lo
 
 // Store new merchant to database
 func (u *merchantRepository) CreateMerchant(entity Merchant) *localError.GlobalError {
-	q := "INSERT INTO merchants (id, name, merchant_category, image_url, location_lat, location_long) values (:id, :name, :merchant_category, :image_url, :location_lat, :location_long);"
+	q := "INSERT INTO merchants (id, name, merchant_category, image_url, location_lat, location_long, preparation_time, timezone) values (:id, :name, :merchant_category, :image_url, :location_lat, :location_long, :preparation_time, :timezone);"
 
 	// Insert into database
 	_, err := u.db.NamedExec(q, &entity)
@@ -69,24 +77,63 @@ func (u *merchantRepository) CreateMerchant(entity Merchant) *localError.GlobalE
 	return nil
 }
 
+// Update merchant data, deleted merchant can not be updated
+func (u *merchantRepository) UpdateMerchant(entity Merchant) *localError.GlobalError {
+	q := `
+		UPDATE merchants
+		SET name = :name, merchant_category = :merchant_category, image_url = :image_url,
+			location_lat = :location_lat, location_long = :location_long, preparation_time = :preparation_time
+		WHERE id = :id AND deleted_at IS NULL
+	`
+
+	res, err := u.db.NamedExec(q, &entity)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Merchant data not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Soft delete merchant, so order that references the merchant is still valid
+func (u *merchantRepository) DeleteMerchant(merchantId string) *localError.GlobalError {
+	q := "UPDATE merchants SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+
+	res, err := u.db.Exec(q, merchantId)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Merchant data not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
 // List all item from database
-func (r *merchantRepository) FindAllItem(param GetItemQueryParam, merchantId string) ([]Item, *localError.GlobalError) {
+// List item of the merchant with the total of item that matches the filter
+func (r *merchantRepository) FindAllItem(param GetItemQueryParam, merchantId string) ([]Item, int, *localError.GlobalError) {
 	// Define emtpy maps of item
 	items := []Item{}
 
-	query := "SELECT * FROM items where 1=1"
+	query := sqlbuilder.New("SELECT * FROM items")
+	query.Where("deleted_at IS NULL")
 
 	// Filter by merhat ID
-	query += fmt.Sprintf(" AND merchant_id = '%s'", merchantId)
+	query.Where("merchant_id = ?", merchantId)
 
 	// Filter by ID
 	if param.ItemID != "" {
-		query += fmt.Sprintf(" AND id = '%s'", param.ItemID)
+		query.Where("id = ?", param.ItemID)
 	}
 
 	// Filter by Name
 	if param.Name != "" {
-		query += fmt.Sprintf(" AND name ILIKE '%%%s%%'", param.Name)
+		query.Where("name ILIKE ?", sqlbuilder.Contains(param.Name))
 	}
 
 	// Filter by Category
@@ -110,37 +157,47 @@ func (r *merchantRepository) FindAllItem(param GetItemQueryParam, merchantId str
 
 	// Filter if category is valid
 	if categoryExists {
-		query += fmt.Sprintf(" AND product_category = '%s'", string(param.ProductCategory))
+		query.Where("product_category = ?", param.ProductCategory)
 	}
 
-	// Sort by created at
-	if param.CreatedAt != "" {
-		query += fmt.Sprintf(" order by created_at %s", string(param.CreatedAt))
-	}
+	// Count every matching item before pagination
+	var total int
 
-	// Set limit & offset
-	if param.Limit != 0 {
-		query += fmt.Sprintf(" LIMIT %d", param.Limit)
-	} else {
-		query += " LIMIT 5"
+	countQ, countArgs := query.Count()
+	if err := r.db.Get(&total, countQ, countArgs...); err != nil {
+		return items, 0, localError.ErrInternalServer(err.Error(), err)
 	}
-	if param.Offset != 0 {
-		query += fmt.Sprintf(" OFFSET %d", param.Offset)
-	} else {
-		query += " OFFSET 0"
+
+	// Sort by created at, ID keeps the page stable
+	direction := sqlbuilder.ParseDirection(string(param.CreatedAt), sqlbuilder.Desc)
+	query.OrderBy("created_at", direction)
+	query.OrderBy("id", direction)
+
+	// Set limit & offset, cursor replaces the offset
+	offset := param.Offset
+	if param.Cursor != "" {
+		cursor, err := sqlbuilder.DecodeCursor(param.Cursor)
+		if err != nil {
+			return items, 0, localError.ErrBadRequest(err.Error(), err)
+		}
+		query.After(cursor, "created_at", "id", direction)
+		offset = 0
 	}
+	query.Paginate(param.Limit, offset)
+
+	q, args := query.Build()
 
-	err := r.db.Select(&items, query)
+	err := r.db.Select(&items, q, args...)
 	if err != nil {
-		return items, localError.ErrInternalServer(err.Error(), err)
+		return items, 0, localError.ErrInternalServer(err.Error(), err)
 	}
 
-	return items, nil
+	return items, total, nil
 }
 
 // Store new item to database
 func (u *merchantRepository) CreateItem(entity Item) *localError.GlobalError {
-	q := "INSERT INTO items (id, merchant_id, name, product_category, price, image_url) values (:id, :merchant_id, :name, :product_category, :price, :image_url);"
+	q := "INSERT INTO items (id, merchant_id, name, product_category, price, image_url, is_available, stock) values (:id, :merchant_id, :name, :product_category, :price, :image_url, :is_available, :stock);"
 
 	// Insert into database
 	_, err := u.db.NamedExec(q, &entity)
@@ -151,61 +208,213 @@ func (u *merchantRepository) CreateItem(entity Item) *localError.GlobalError {
 	return nil
 }
 
-func (r *merchantRepository) FindAllMerchants(params GetMerchantQueryParams) ([]Merchant, *localError.GlobalError) {
+// Find item of the merchant, deleted item is not found
+func (u *merchantRepository) FindItemById(merchantId string, itemId string) (*Item, *localError.GlobalError) {
+	item := Item{}
+
+	q := "SELECT * FROM items WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL"
+	if err := u.db.Get(&item, q, itemId, merchantId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, localError.ErrNotFound("Item data not found", err)
+		}
+
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &item, nil
+}
+
+// Update item data, deleted item can not be updated.
+// Stock is only written when it is changed, so it won't overwrite stock reserved by order.
+// Both writes are done in a single transaction, so the item is never partially updated.
+func (u *merchantRepository) UpdateItem(entity Item, stockChanged bool) *localError.GlobalError {
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
+	q := `
+		UPDATE items
+		SET name = :name, product_category = :product_category, price = :price,
+			image_url = :image_url, is_available = :is_available
+		WHERE id = :id AND merchant_id = :merchant_id AND deleted_at IS NULL
+	`
+
+	res, err := tx.NamedExec(q, &entity)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Item data not found", sql.ErrNoRows)
+	}
+
+	if stockChanged {
+		stockQ := "UPDATE items SET stock = $1 WHERE id = $2 AND merchant_id = $3"
+
+		if _, err := tx.Exec(stockQ, entity.Stock, entity.ID, entity.MerchantID); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Soft delete item, so order that references the item is still valid
+func (u *merchantRepository) DeleteItem(merchantId string, itemId string) *localError.GlobalError {
+	q := "UPDATE items SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND merchant_id = $2 AND deleted_at IS NULL"
+
+	res, err := u.db.Exec(q, itemId, merchantId)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Item data not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Get option groups of the items together with its options
+func (r *merchantRepository) FindOptionGroups(itemIDs []string) ([]OptionGroup, *localError.GlobalError) {
+	groups := []OptionGroup{}
+	options := []Option{}
+
+	if len(itemIDs) == 0 {
+		return groups, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM item_option_groups WHERE item_id in (?) ORDER BY item_id, position", itemIDs)
+	if err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if err := r.db.Select(&groups, r.db.Rebind(query), args...); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if len(groups) == 0 {
+		return groups, nil
+	}
+
+	groupIndex := make(map[string]int)
+	groupIDs := []string{}
+	for i, group := range groups {
+		groupIndex[group.ID] = i
+		groupIDs = append(groupIDs, group.ID)
+		groups[i].Options = []Option{}
+	}
+
+	query, args, err = sqlx.In("SELECT * FROM item_options WHERE option_group_id in (?) ORDER BY option_group_id, position", groupIDs)
+	if err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if err := r.db.Select(&options, r.db.Rebind(query), args...); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	for _, option := range options {
+		ix := groupIndex[option.OptionGroupID]
+		groups[ix].Options = append(groups[ix].Options, option)
+	}
+
+	return groups, nil
+}
+
+// Replace every option group of the item in a single transaction
+func (r *merchantRepository) ReplaceOptionGroups(itemId string, groups []OptionGroup) *localError.GlobalError {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
+	// Options are deleted by cascade
+	if _, err := tx.Exec("DELETE FROM item_option_groups WHERE item_id = $1", itemId); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	for _, group := range groups {
+		q := "INSERT INTO item_option_groups (id, item_id, name, min_select, max_select, position) values (:id, :item_id, :name, :min_select, :max_select, :position);"
+		if _, err := tx.NamedExec(q, &group); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+
+		q = "INSERT INTO item_options (id, option_group_id, name, price_delta, position) values (:id, :option_group_id, :name, :price_delta, :position);"
+		if _, err := tx.NamedExec(q, group.Options); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// List merchant with the total of merchant that matches the filter
+func (r *merchantRepository) FindAllMerchants(params GetMerchantQueryParams) ([]Merchant, int, *localError.GlobalError) {
 	merchants := []Merchant{}
 
-	query := "SELECT * FROM merchants"
-	nwhere := 0
+	// Deleted merchant is never listed
+	query := sqlbuilder.New("SELECT * FROM merchants")
+	query.Where("deleted_at IS NULL")
 
 	if params.MerchantID != "" {
-		nwhere += 1
-		query += fmt.Sprintf(" WHERE id = '%s'", params.MerchantID)
+		query.Where("id = ?", params.MerchantID)
 	}
 
 	if params.Name != "" {
-		prefix := "WHERE"
-		if nwhere > 0 {
-			prefix = "AND"
-		}
-		nwhere += 1
-		query += fmt.Sprintf(" %s name ILIKE '%%%s%%'", prefix, params.Name)
+		query.Where("name ILIKE ?", sqlbuilder.Contains(params.Name))
 	}
 
 	if params.MerchantCategory == "SmallRestaurant" || params.MerchantCategory == "MediumRestaurant" || params.MerchantCategory == "LargeRestaurant" || params.MerchantCategory == "MerchandiseRestaurant" || params.MerchantCategory == "BoothKiosk" || params.MerchantCategory == "ConvenienceStore" {
-		prefix := "WHERE"
-		if nwhere > 0 {
-			prefix = "AND"
-		}
-		nwhere += 1
-		query += fmt.Sprintf(" %s merchant_category = '%s'", prefix, params.MerchantCategory)
+		query.Where("merchant_category = ?", params.MerchantCategory)
 	}
 
-	if params.CreatedAt == "asc" || params.CreatedAt == "desc" {
-		query += fmt.Sprintf(" ORDER BY created_at %s", params.CreatedAt)
-	} else {
-		query += " ORDER BY created_at desc"
-	}
+	// Count every matching merchant before pagination
+	var total int
 
-	if params.Limit != 0 {
-		query += fmt.Sprintf(" LIMIT %d", params.Limit)
-	} else {
-		query += " LIMIT 5"
+	countQ, countArgs := query.Count()
+	if err := r.db.Get(&total, countQ, countArgs...); err != nil {
+		return merchants, 0, localError.ErrInternalServer(err.Error(), err)
 	}
-	if params.Offset != 0 {
-		query += fmt.Sprintf(" OFFSET %d", params.Offset)
-	} else {
-		query += " OFFSET 0"
+
+	// ID keeps the page stable
+	direction := sqlbuilder.ParseDirection(string(params.CreatedAt), sqlbuilder.Desc)
+	query.OrderBy("created_at", direction)
+	query.OrderBy("id", direction)
+
+	// Cursor replaces the offset
+	offset := params.Offset
+	if params.Cursor != "" {
+		cursor, err := sqlbuilder.DecodeCursor(params.Cursor)
+		if err != nil {
+			return merchants, 0, localError.ErrBadRequest(err.Error(), err)
+		}
+		query.After(cursor, "created_at", "id", direction)
+		offset = 0
 	}
+	query.Paginate(params.Limit, offset)
 
-	// log.Println(query)
+	q, args := query.Build()
+
+	// log.Println(q)
 
-	err := r.db.Select(&merchants, query)
+	err := r.db.Select(&merchants, q, args...)
 	if err != nil {
-		log.Println(err)
-		return merchants, nil //localError.ErrInternalServer("Failed to find merchants", err)
+		return merchants, 0, localError.ErrInternalServer(err.Error(), err)
 	}
 
-	return merchants, nil
+	return merchants, total, nil
 }
 
 // Count and check if any merchant id provided in parameter is exists.
@@ -215,7 +424,7 @@ func (u *merchantRepository) CheckMerchantIDs(IDs []string) ([]Merchant, *localE
 	q := `
 		SELECT *
 		FROM merchants
-		WHERE id in (?)
+		WHERE id in (?) AND deleted_at IS NULL
 	`
 
 	// Fill the quety with the place holder
@@ -245,7 +454,7 @@ func (u *merchantRepository) CheckItemIDs(IDs []string) ([]Item, *localError.Glo
 	q := `
 		SELECT *
 		FROM items
-		WHERE id in (?)
+		WHERE id in (?) AND deleted_at IS NULL
 	`
 
 	// Fill the quety with the place holder
@@ -285,67 +494,111 @@ func (u *merchantRepository) CheckItemIDs(IDs []string) ([]Item, *localError.Glo
 // 	return placeholder
 // }
 
-func (r *merchantRepository) FindNearbyMerchants(location Location, params GetMerchantQueryParams) ([]MerchantWithItemQueryResult, *localError.GlobalError) {
+// Find merchants ordered by the nearest one together with its available items.
+// Pagination is applied on the merchants, so a page always contains every item of its merchants.
+func (r *merchantRepository) FindNearbyMerchants(location Location, params GetMerchantQueryParams) ([]MerchantWithItemQueryResult, int, *localError.GlobalError) {
 	merchants := []MerchantWithItemQueryResult{}
 
-	subquery := `
-	select
-	m.id as merchant_id,
-	m.name as merchant_name,
-	merchant_category,
-	m.image_url as merchant_image_url,
-	location_lat,
-	location_long,
-	m.created_at as merchant_created_at,
-	i.id as item_id,
-	i.name as item_name,
-	product_category,
-	price,
-	i.image_url as item_image_url,
-	i.created_at as item_created_at 
-	from merchants m inner join items i on m.id = i.merchant_id` 
-	nwhere := 0
+	// Condition of the listed items, merchant without any listed item is not listed
+	itemConditions := []string{"i.deleted_at IS NULL", "i.is_available"}
+
+	filter := sqlbuilder.New("SELECT m.id, m.location_lat, m.location_long, m.rating_average FROM merchants m")
+	filter.Where("m.deleted_at IS NULL")
 
 	if params.MerchantID != "" {
-		nwhere += 1
-		subquery += fmt.Sprintf(" WHERE m.id = '%s'", params.MerchantID)
+		filter.Where("m.id = ?", params.MerchantID)
 	}
 
 	if params.Name != "" {
-		prefix := "WHERE"
-		if nwhere > 0 {
-			prefix = "AND"
-		}
-		nwhere += 1
-		subquery += fmt.Sprintf(" %s (m.name ILIKE '%%%s%%' OR i.name ILIKE '%%%s%%')", prefix, params.Name, params.Name)
+		name := filter.Arg(sqlbuilder.Contains(params.Name))
+		itemConditions = append(itemConditions, fmt.Sprintf("(m.name ILIKE %s OR i.name ILIKE %s)", name, name))
 	}
 
 	if params.MerchantCategory == "SmallRestaurant" || params.MerchantCategory == "MediumRestaurant" || params.MerchantCategory == "LargeRestaurant" || params.MerchantCategory == "MerchandiseRestaurant" || params.MerchantCategory == "BoothKiosk" || params.MerchantCategory == "ConvenienceStore" {
-		prefix := "WHERE"
-		if nwhere > 0 {
-			prefix = "AND"
+		filter.Where("m.merchant_category = ?", params.MerchantCategory)
+	}
+
+	// Only merchant that is open now based on its opening hours and closures
+	if params.OpenNow {
+		filter.Where("is_merchant_open(m.id, now())")
+	}
+
+	// Merchant without any review has no rating
+	if params.MinRating > 0 {
+		filter.Where("m.rating_count > 0 AND m.rating_average >= ?", params.MinRating)
+	}
+
+	// Bounding box uses the location index, so the exact distance is only calculated for merchants around the user
+	if params.MaxDistance > 0 {
+		box := distances.NewBoundingBox(distances.Point{Lat: location.Lat, Long: location.Long}, params.MaxDistance)
+		filter.Where("m.location_lat BETWEEN ? AND ?", box.MinLat, box.MaxLat)
+		if box.MinLong > -180 || box.MaxLong < 180 {
+			filter.Where("m.location_long BETWEEN ? AND ?", box.MinLong, box.MaxLong)
 		}
-		nwhere += 1
-		subquery += fmt.Sprintf(" %s merchant_category = '%s'", prefix, params.MerchantCategory)
+		filter.Where("calculate_distance(?, ?, m.location_lat, m.location_long) <= ?", location.Lat, location.Long, params.MaxDistance)
 	}
 
+	itemCondition := strings.Join(itemConditions, " AND ")
+	filter.Where(fmt.Sprintf("EXISTS (SELECT 1 FROM items i WHERE i.merchant_id = m.id AND %s)", itemCondition))
+
+	// Count every matching merchant before pagination
+	var total int
+
+	countQ, countArgs := filter.Count()
+	if err := r.db.Get(&total, countQ, countArgs...); err != nil {
+		return merchants, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	lat := filter.Arg(location.Lat)
+	long := filter.Arg(location.Long)
+	limit, offset := sqlbuilder.Page(params.Limit, params.Offset)
+
+	// Nearest merchant comes first, unless it is sorted by the best rating
+	nearbyOrder, resultOrder := "distance asc, f.id", "n.distance asc, m.id, i.created_at"
+	if params.SortBy == "rating" {
+		nearbyOrder, resultOrder = "f.rating_average desc, distance asc, f.id", "n.rating_average desc, n.distance asc, m.id, i.created_at"
+	}
+
+	// Same formula as distances.Calculate
 	query := fmt.Sprintf(`
-	WITH myconstant (rad) as (
-		values (pi()/180)
-	 ),
-	 mydata as (%s)
+	WITH nearby as (
+		select
+		calculate_distance(%s, %s, f.location_lat, f.location_long) as distance,
+		f.id,
+		f.rating_average
+		from (%s) f
+		order by %s
+		limit %s offset %s
+	 )
 	 select
-	 2 * 6371 * asin( |/( sin((%f*rad-location_lat*rad)/2::decimal)^2 + (sin((%f*rad-location_long*rad)/2::decimal)^2) * cos(location_lat*rad) * cos(%f*rad) ) ) as distance,
-	 mydata.* from mydata, myconstant
-	 order by distance asc;`, subquery, location.Lat, location.Long, location.Lat)
+	 n.distance,
+	 m.id as merchant_id,
+	 m.name as merchant_name,
+	 m.merchant_category,
+	 m.image_url as merchant_image_url,
+	 m.location_lat,
+	 m.location_long,
+	 m.created_at as merchant_created_at,
+	 i.id as item_id,
+	 i.name as item_name,
+	 i.product_category,
+	 i.price,
+	 i.image_url as item_image_url,
+	 i.created_at as item_created_at,
+	 m.rating_average,
+	 m.rating_count
+	 from nearby n
+	 inner join merchants m on m.id = n.id
+	 inner join items i on i.merchant_id = m.id
+	 where %s
+	 order by %s;`, lat, long, filter.String(), nearbyOrder, filter.Arg(limit), filter.Arg(offset), itemCondition, resultOrder)
 
 	// log.Println(query)
 
-	err := r.db.Select(&merchants, query)
+	err := r.db.Select(&merchants, query, filter.Args()...)
 	if err != nil {
-		log.Println(err)
-		return merchants, nil
+		return merchants, 0, localError.ErrInternalServer(err.Error(), err)
 	}
 
-	return merchants, nil
+	return merchants, total, nil
 }
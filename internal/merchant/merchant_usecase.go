@@ -3,30 +3,49 @@ package merchant
 import (
 	// "errors"
 	localError "belimang/pkg/error"
+	"belimang/pkg/sqlbuilder"
+	"database/sql"
+	"fmt"
 	// "strconv"
-	// "time"
+	"time"
 	"github.com/google/uuid"
 	// "log"
 )
 
 type IMerchantUsecase interface {
 	CreateMerchant(req CreateMerchantDTO) (*CreateMerchantResponse, *localError.GlobalError)
+	UpdateMerchant(merchantId string, req UpdateMerchantDTO) (*GetMerchantResponse, *localError.GlobalError)
+	DeleteMerchant(merchantId string) *localError.GlobalError
 	CreateItem(merchantId string, req CreateItemDTO) (*CreateItemResponse, *localError.GlobalError)
+	UpdateItem(merchantId string, itemId string, req UpdateItemDTO) (*ItemResponse, *localError.GlobalError)
+	DeleteItem(merchantId string, itemId string) *localError.GlobalError
+	FindItemOptionGroups(merchantId string, itemId string) ([]OptionGroupResponse, *localError.GlobalError)
+	UpdateItemOptionGroups(merchantId string, itemId string, req UpdateOptionGroupsDTO) ([]OptionGroupResponse, *localError.GlobalError)
+	FindOptionGroups(itemIDs []string) ([]OptionGroup, *localError.GlobalError)
 	FindAllMerchants(query GetMerchantQueryParams) (GetMerchantResponseAndMeta, *localError.GlobalError)
 	FindMerchantById(id string) (*Merchant, *localError.GlobalError)
 	FindAllItem(query GetItemQueryParam, merchatId string) (ItemResponseAndMeta, *localError.GlobalError)
 	CheckMerchantIDs(IDs []string) ([]Merchant, *localError.GlobalError)
 	CheckItemIDs(IDs []string) ([]Item, *localError.GlobalError)
 	FindNearbyMerchants(location Location, query GetMerchantQueryParams) (NearbyMerchantWithItemResponseAndMeta, *localError.GlobalError)
+	FindOpeningHours(merchantId string) (*OpeningHoursResponse, *localError.GlobalError)
+	UpdateOpeningHours(merchantId string, req UpdateOpeningHoursDTO) (*OpeningHoursResponse, *localError.GlobalError)
+	FindClosures(merchantId string) ([]ClosureResponse, *localError.GlobalError)
+	CreateClosure(merchantId string, req CreateClosureDTO) (*ClosureResponse, *localError.GlobalError)
+	DeleteClosure(merchantId string, closureId string) *localError.GlobalError
+	CheckMerchantsOpen(IDs []string, at time.Time) *localError.GlobalError
+	FindClosedMerchants(IDs []string, at time.Time) (map[string]string, *localError.GlobalError)
 }
 
 type merchantUsecase struct {
-	repo IMerchantRepository
+	repo         IMerchantRepository
+	scheduleRepo IScheduleRepository
 }
 
-func NewMerchantUsecase(repo IMerchantRepository) IMerchantUsecase {
+func NewMerchantUsecase(repo IMerchantRepository, scheduleRepo IScheduleRepository) IMerchantUsecase {
 	return &merchantUsecase{
-		repo: repo,
+		repo:         repo,
+		scheduleRepo: scheduleRepo,
 	}
 }
 
@@ -38,6 +57,19 @@ func (uc *merchantUsecase) CreateMerchant(req CreateMerchantDTO) (*CreateMerchan
 		ImageUrl:         req.ImageUrl,
 		LocationLat:      req.Location.Lat,
 		LocationLong:     req.Location.Long,
+		Timezone:         DefaultTimezone,
+	}
+
+	if req.Timezone != "" {
+		if !IsValidTimezone(req.Timezone) {
+			return nil, localError.ErrBadRequest("Timezone is not valid", fmt.Errorf("invalid timezone %s", req.Timezone))
+		}
+
+		merchant.Timezone = req.Timezone
+	}
+
+	if req.PreparationTime != nil {
+		merchant.PreparationTime = sql.NullInt32{Int32: int32(*req.PreparationTime), Valid: true}
 	}
 
 	err := uc.repo.CreateMerchant(merchant)
@@ -52,6 +84,44 @@ func (uc *merchantUsecase) CreateMerchant(req CreateMerchantDTO) (*CreateMerchan
 	return &response, nil
 }
 
+func (uc *merchantUsecase) UpdateMerchant(merchantId string, req UpdateMerchantDTO) (*GetMerchantResponse, *localError.GlobalError) {
+	merchant, err := uc.repo.FindMerchantById(merchantId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only update the given field
+	if req.Name != nil {
+		merchant.Name = *req.Name
+	}
+	if req.MerchantCategory != nil {
+		merchant.MerchantCategory = *req.MerchantCategory
+	}
+	if req.ImageUrl != nil {
+		merchant.ImageUrl = *req.ImageUrl
+	}
+	if req.Location != nil {
+		merchant.LocationLat = req.Location.Lat
+		merchant.LocationLong = req.Location.Long
+	}
+	if req.PreparationTime != nil {
+		merchant.PreparationTime = sql.NullInt32{Int32: int32(*req.PreparationTime), Valid: true}
+	}
+
+	err = uc.repo.UpdateMerchant(*merchant)
+	if err != nil {
+		return nil, err
+	}
+
+	response := FormatGetMerchantResponse([]Merchant{*merchant})[0]
+
+	return &response, nil
+}
+
+func (uc *merchantUsecase) DeleteMerchant(merchantId string) *localError.GlobalError {
+	return uc.repo.DeleteMerchant(merchantId)
+}
+
 func (uc *merchantUsecase) CreateItem(merchantId string, req CreateItemDTO) (*CreateItemResponse, *localError.GlobalError) {
 	_, err := uc.repo.FindMerchantById(merchantId)
 	if err != nil {
@@ -65,6 +135,15 @@ func (uc *merchantUsecase) CreateItem(merchantId string, req CreateItemDTO) (*Cr
 		ProductCategory: req.ProductCategory,
 		Price:           req.Price,
 		ImageUrl:        req.ImageUrl,
+		IsAvailable:     true,
+	}
+
+	if req.IsAvailable != nil {
+		item.IsAvailable = *req.IsAvailable
+	}
+
+	if req.Stock != nil {
+		item.Stock = sql.NullInt32{Int32: int32(*req.Stock), Valid: true}
 	}
 
 	err = uc.repo.CreateItem(item)
@@ -79,10 +158,163 @@ func (uc *merchantUsecase) CreateItem(merchantId string, req CreateItemDTO) (*Cr
 	return &response, nil
 }
 
+func (uc *merchantUsecase) UpdateItem(merchantId string, itemId string, req UpdateItemDTO) (*ItemResponse, *localError.GlobalError) {
+	item, err := uc.repo.FindItemById(merchantId, itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only update the given field
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.ProductCategory != nil {
+		item.ProductCategory = *req.ProductCategory
+	}
+	if req.Price != nil {
+		item.Price = *req.Price
+	}
+	if req.ImageUrl != nil {
+		item.ImageUrl = *req.ImageUrl
+	}
+	if req.IsAvailable != nil {
+		item.IsAvailable = *req.IsAvailable
+	}
+
+	// Stock is only written when it is changed
+	stockChanged := true
+	switch {
+	case req.TrackStock != nil && !*req.TrackStock:
+		item.Stock = sql.NullInt32{}
+	case req.Stock != nil:
+		item.Stock = sql.NullInt32{Int32: int32(*req.Stock), Valid: true}
+	case req.TrackStock != nil && !item.Stock.Valid:
+		// Start tracking with empty stock
+		item.Stock = sql.NullInt32{Valid: true}
+	default:
+		stockChanged = false
+	}
+
+	err = uc.repo.UpdateItem(*item, stockChanged)
+	if err != nil {
+		return nil, err
+	}
+
+	response := FormatItemResponse([]Item{*item})[0]
+
+	return &response, nil
+}
+
+func (uc *merchantUsecase) DeleteItem(merchantId string, itemId string) *localError.GlobalError {
+	return uc.repo.DeleteItem(merchantId, itemId)
+}
+
+func (uc *merchantUsecase) FindItemOptionGroups(merchantId string, itemId string) ([]OptionGroupResponse, *localError.GlobalError) {
+	if _, err := uc.repo.FindItemById(merchantId, itemId); err != nil {
+		return nil, err
+	}
+
+	groups, err := uc.repo.FindOptionGroups([]string{itemId})
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatOptionGroupResponse(groups), nil
+}
+
+func (uc *merchantUsecase) UpdateItemOptionGroups(merchantId string, itemId string, req UpdateOptionGroupsDTO) ([]OptionGroupResponse, *localError.GlobalError) {
+	if _, err := uc.repo.FindItemById(merchantId, itemId); err != nil {
+		return nil, err
+	}
+
+	groups := []OptionGroup{}
+	for i, v := range req.OptionGroups {
+		// User can't select more options than the group has
+		if v.MaxSelect > len(v.Options) {
+			message := fmt.Sprintf("maxSelect of %s is more than its options", v.Name)
+			return nil, localError.ErrBadRequest(message, fmt.Errorf("option group %s max select is %d, options is %d", v.Name, v.MaxSelect, len(v.Options)))
+		}
+
+		group := OptionGroup{
+			ID:        uuid.NewString(),
+			ItemID:    itemId,
+			Name:      v.Name,
+			MinSelect: *v.MinSelect,
+			MaxSelect: v.MaxSelect,
+			Position:  i,
+			Options:   []Option{},
+		}
+
+		for j, o := range v.Options {
+			group.Options = append(group.Options, Option{
+				ID:            uuid.NewString(),
+				OptionGroupID: group.ID,
+				Name:          o.Name,
+				PriceDelta:    *o.PriceDelta,
+				Position:      j,
+			})
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err := uc.repo.ReplaceOptionGroups(itemId, groups); err != nil {
+		return nil, err
+	}
+
+	return FormatOptionGroupResponse(groups), nil
+}
+
+func (uc *merchantUsecase) FindOptionGroups(itemIDs []string) ([]OptionGroup, *localError.GlobalError) {
+	return uc.repo.FindOptionGroups(itemIDs)
+}
+
+// Get option groups of the items grouped by item ID
+func (uc *merchantUsecase) findOptionGroupsByItem(itemIDs []string) (map[string][]OptionGroup, *localError.GlobalError) {
+	groups, err := uc.repo.FindOptionGroups(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsByItem := make(map[string][]OptionGroup)
+	for _, group := range groups {
+		groupsByItem[group.ItemID] = append(groupsByItem[group.ItemID], group)
+	}
+
+	return groupsByItem, nil
+}
+
 type Meta struct {
 	Limit int `json:"limit"`
 	Offset int `json:"offset"`
 	Total int `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// Pagination meta of list response.
+// Total is the count of every row that matches the filter, not only the current page.
+func NewMeta(limit int, offset int, total int) Meta {
+	limit, offset = sqlbuilder.Page(limit, offset)
+
+	return Meta{
+		Limit:  limit,
+		Offset: offset,
+		Total:  total,
+	}
+}
+
+// Attach the next page cursor from the last row of the page.
+// Offset is not used when the page is requested with a cursor.
+func (m Meta) WithCursor(cursor string, rows int, last sqlbuilder.Cursor) Meta {
+	if cursor != "" {
+		m.Offset = 0
+	}
+
+	if rows > 0 {
+		m.NextCursor = sqlbuilder.NextCursor(rows, m.Limit, last)
+	}
+
+	return m
 }
 
 type NearbyMerchantWithItemResponseAndMeta struct {
@@ -101,31 +333,21 @@ type ItemResponseAndMeta struct {
 }
 
 func (uc *merchantUsecase) FindAllMerchants(query GetMerchantQueryParams) (GetMerchantResponseAndMeta, *localError.GlobalError) {
-	merchants, err := uc.repo.FindAllMerchants(query)
+	merchants, total, err := uc.repo.FindAllMerchants(query)
 	if err != nil {
 		return GetMerchantResponseAndMeta{}, err
 	}
 
 	resp := FormatGetMerchantResponse(merchants)
 
-	limit := 5
-	offset := 0
-	if query.Limit != 0 {
-		limit = query.Limit
-	}
-	if query.Offset != 0 {
-		offset = query.Offset
+	last := sqlbuilder.Cursor{}
+	if len(merchants) > 0 {
+		last = sqlbuilder.Cursor{CreatedAt: merchants[len(merchants)-1].CreatedAt, ID: merchants[len(merchants)-1].ID}
 	}
 
-	meta := Meta{
-		Limit: limit,
-		Offset: offset,
-		Total: len(resp),
-	}
-	
 	return GetMerchantResponseAndMeta{
 		Data: resp,
-		Meta: meta,
+		Meta: NewMeta(query.Limit, query.Offset, total).WithCursor(query.Cursor, len(merchants), last),
 	}, nil
 }
 
@@ -140,7 +362,7 @@ func (uc *merchantUsecase) FindAllItem(query GetItemQueryParam, merchantId strin
 		return ItemResponseAndMeta{}, err
 	}
 
-	items, err := uc.repo.FindAllItem(query, merchantId)
+	items, total, err := uc.repo.FindAllItem(query, merchantId)
 
 	if err != nil {
 		return ItemResponseAndMeta{}, err
@@ -148,64 +370,68 @@ func (uc *merchantUsecase) FindAllItem(query GetItemQueryParam, merchantId strin
 
 	response := FormatItemResponse(items)
 
-	limit := 5
-	offset := 0
-	if query.Limit != 0 {
-		limit = query.Limit
+	// Attach option groups of every item
+	itemIDs := []string{}
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
 	}
-	if query.Offset != 0 {
-		offset = query.Offset
+
+	groupsByItem, err := uc.findOptionGroupsByItem(itemIDs)
+	if err != nil {
+		return ItemResponseAndMeta{}, err
 	}
 
-	meta := Meta{
-		Limit: limit,
-		Offset: offset,
-		Total: len(response),
+	for i := range response {
+		response[i].OptionGroups = FormatOptionGroupResponse(groupsByItem[response[i].ID])
+	}
+
+	last := sqlbuilder.Cursor{}
+	if len(items) > 0 {
+		last = sqlbuilder.Cursor{CreatedAt: items[len(items)-1].CreatedAt, ID: items[len(items)-1].ID}
 	}
 
 	return ItemResponseAndMeta{
 		Data: response,
-		Meta: meta,
+		Meta: NewMeta(query.Limit, query.Offset, total).WithCursor(query.Cursor, len(items), last),
 	}, nil
 }
 
+// FindNearbyMerchants list merchants sorted by distance or rating from the location.
+// Nearby merchants are paginated by offset only, since the sort depends on the requested location.
 func (uc *merchantUsecase) FindNearbyMerchants(location Location, query GetMerchantQueryParams) (NearbyMerchantWithItemResponseAndMeta, *localError.GlobalError) {
-	merchants, err := uc.repo.FindNearbyMerchants(location, query)
-  	if err != nil {
-		return NearbyMerchantWithItemResponseAndMeta{}, err
+	if query.Cursor != "" {
+		return NearbyMerchantWithItemResponseAndMeta{}, localError.ErrBadRequest("cursor is not supported by nearby merchants, use offset instead", fmt.Errorf("cursor is given on nearby merchants"))
 	}
-  
-  	resp := FormatNearbyMerchantWithItemResponse(merchants)
 
-	limit := 5
-	offset := 0
-	if query.Limit != 0 {
-		limit = query.Limit
-	}
-	if query.Offset != 0 {
-		offset = query.Offset
+	merchants, total, err := uc.repo.FindNearbyMerchants(location, query)
+	if err != nil {
+		return NearbyMerchantWithItemResponseAndMeta{}, err
 	}
 
-	if offset >= len(resp) {
-		return NearbyMerchantWithItemResponseAndMeta{}, nil
-	}
-	if limit < 0 {
-		return NearbyMerchantWithItemResponseAndMeta{}, nil
+	resp := FormatNearbyMerchantWithItemResponse(merchants)
+
+	// Attach option groups of every item on the page
+	itemIDs := []string{}
+	for _, m := range resp {
+		for _, item := range m.Items {
+			itemIDs = append(itemIDs, item.ID)
+		}
 	}
-	if offset+limit > len(resp) {
-		cut := offset+limit-len(resp)
-		limit = limit-cut
+
+	groupsByItem, err := uc.findOptionGroupsByItem(itemIDs)
+	if err != nil {
+		return NearbyMerchantWithItemResponseAndMeta{}, err
 	}
 
-	meta := Meta{
-		Limit: limit,
-		Offset: offset,
-		Total: len(resp),
+	for _, m := range resp {
+		for i := range m.Items {
+			m.Items[i].OptionGroups = FormatOptionGroupResponse(groupsByItem[m.Items[i].ID])
+		}
 	}
-	
+
 	return NearbyMerchantWithItemResponseAndMeta{
-		Data: resp[offset:offset+limit],
-		Meta: meta,
+		Data: resp,
+		Meta: NewMeta(query.Limit, query.Offset, total),
 	}, nil
 }
 
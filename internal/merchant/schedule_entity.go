@@ -0,0 +1,116 @@
+package merchant
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Default timezone of merchant opening hours
+const DefaultTimezone = "Asia/Jakarta"
+
+// Check if the timezone is an IANA timezone name that postgres AT TIME ZONE accepts.
+// Go accepts "Local" and empty string as timezone, but postgres rejects both.
+func IsValidTimezone(tz string) bool {
+	if tz == "" || tz == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(tz)
+
+	return err == nil
+}
+
+type OpeningHour struct {
+	MerchantID string `json:"-" db:"merchant_id"`
+	DayOfWeek  int    `json:"dayOfWeek" db:"day_of_week"` // 0 is Sunday
+	OpenTime   string `json:"open" db:"open_time"`        // HH:MM in merchant timezone
+	CloseTime  string `json:"close" db:"close_time"`      // HH:MM, before open time means closing on the next day
+}
+
+type MerchantClosure struct {
+	ID         string         `json:"closureId" db:"id"`
+	MerchantID string         `json:"-" db:"merchant_id"`
+	StartAt    time.Time      `json:"startAt" db:"start_at"`
+	EndAt      time.Time      `json:"endAt" db:"end_at"`
+	Reason     sql.NullString `json:"-" db:"reason"`
+	CreatedAt  time.Time      `json:"-" db:"created_at"`
+}
+
+// Open state of a merchant at a given time
+type MerchantAvailability struct {
+	MerchantID    string         `db:"id"`
+	Name          string         `db:"name"`
+	IsOpen        bool           `db:"is_open"`
+	ClosureEndAt  sql.NullTime   `db:"closure_end_at"`
+	ClosureReason sql.NullString `db:"closure_reason"`
+}
+
+type OpeningHourDTO struct {
+	DayOfWeek *int   `json:"dayOfWeek" binding:"required,min=0,max=6"`
+	Open      string `json:"open" binding:"required,datetime=15:04"`
+	Close     string `json:"close" binding:"required,datetime=15:04"`
+}
+
+// Replace the whole weekly opening hours, empty opening hours means always open
+type UpdateOpeningHoursDTO struct {
+	Timezone     string           `json:"timezone" binding:"required,timezone"`
+	OpeningHours []OpeningHourDTO `json:"openingHours" binding:"dive"`
+}
+
+type CreateClosureDTO struct {
+	StartAt time.Time `json:"startAt" binding:"required"`
+	EndAt   time.Time `json:"endAt" binding:"required,gtfield=StartAt"`
+	Reason  string    `json:"reason" binding:"max=100"`
+}
+
+type OpeningHourResponse struct {
+	DayOfWeek int    `json:"dayOfWeek"`
+	Open      string `json:"open"`
+	Close     string `json:"close"`
+}
+
+type OpeningHoursResponse struct {
+	MerchantID   string                `json:"merchantId"`
+	Timezone     string                `json:"timezone"`
+	OpeningHours []OpeningHourResponse `json:"openingHours"`
+}
+
+type ClosureResponse struct {
+	ClosureID string `json:"closureId"`
+	StartAt   string `json:"startAt"`
+	EndAt     string `json:"endAt"`
+	Reason    string `json:"reason"`
+}
+
+func FormatOpeningHoursResponse(merchant Merchant, hours []OpeningHour) OpeningHoursResponse {
+	response := OpeningHoursResponse{
+		MerchantID:   merchant.ID,
+		Timezone:     merchant.Timezone,
+		OpeningHours: []OpeningHourResponse{},
+	}
+
+	for _, hour := range hours {
+		response.OpeningHours = append(response.OpeningHours, OpeningHourResponse{
+			DayOfWeek: hour.DayOfWeek,
+			Open:      hour.OpenTime,
+			Close:     hour.CloseTime,
+		})
+	}
+
+	return response
+}
+
+func FormatClosureResponse(closures []MerchantClosure) []ClosureResponse {
+	response := []ClosureResponse{}
+
+	for _, closure := range closures {
+		response = append(response, ClosureResponse{
+			ClosureID: closure.ID,
+			StartAt:   closure.StartAt.Format(time.RFC3339),
+			EndAt:     closure.EndAt.Format(time.RFC3339),
+			Reason:    closure.Reason.String,
+		})
+	}
+
+	return response
+}
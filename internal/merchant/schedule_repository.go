@@ -0,0 +1,154 @@
+package merchant
+
+import (
+	localError "belimang/pkg/error"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IScheduleRepository interface {
+	FindOpeningHours(merchantId string) ([]OpeningHour, *localError.GlobalError)
+	ReplaceOpeningHours(merchantId string, timezone string, hours []OpeningHour) *localError.GlobalError
+	FindClosures(merchantId string, endAfter time.Time) ([]MerchantClosure, *localError.GlobalError)
+	CreateClosure(entity MerchantClosure) *localError.GlobalError
+	DeleteClosure(merchantId string, closureId string) *localError.GlobalError
+	FindAvailability(IDs []string, at time.Time) ([]MerchantAvailability, *localError.GlobalError)
+}
+
+type scheduleRepository struct {
+	db *sqlx.DB
+}
+
+func NewScheduleRepository(db *sqlx.DB) IScheduleRepository {
+	return &scheduleRepository{
+		db: db,
+	}
+}
+
+// Get weekly opening hours of the merchant ordered by day
+func (r *scheduleRepository) FindOpeningHours(merchantId string) ([]OpeningHour, *localError.GlobalError) {
+	hours := []OpeningHour{}
+
+	q := `
+		SELECT merchant_id, day_of_week,
+			to_char(open_time, 'HH24:MI') as open_time,
+			to_char(close_time, 'HH24:MI') as close_time
+		FROM merchant_opening_hours
+		WHERE merchant_id = $1
+		ORDER BY day_of_week
+	`
+
+	if err := r.db.Select(&hours, q, merchantId); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return hours, nil
+}
+
+// Replace merchant timezone and the whole weekly opening hours in a single transaction
+func (r *scheduleRepository) ReplaceOpeningHours(merchantId string, timezone string, hours []OpeningHour) *localError.GlobalError {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE merchants SET timezone = $1 WHERE id = $2 AND deleted_at IS NULL", timezone, merchantId)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Merchant data not found", sql.ErrNoRows)
+	}
+
+	if _, err := tx.Exec("DELETE FROM merchant_opening_hours WHERE merchant_id = $1", merchantId); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if len(hours) > 0 {
+		q := `INSERT INTO merchant_opening_hours (merchant_id, day_of_week, open_time, close_time)
+			VALUES (:merchant_id, :day_of_week, :open_time, :close_time)`
+
+		if _, err := tx.NamedExec(q, hours); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Get closures of the merchant that end after the given time
+func (r *scheduleRepository) FindClosures(merchantId string, endAfter time.Time) ([]MerchantClosure, *localError.GlobalError) {
+	closures := []MerchantClosure{}
+
+	q := "SELECT * FROM merchant_closures WHERE merchant_id = $1 AND end_at > $2 ORDER BY start_at"
+
+	if err := r.db.Select(&closures, q, merchantId, endAfter); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return closures, nil
+}
+
+func (r *scheduleRepository) CreateClosure(entity MerchantClosure) *localError.GlobalError {
+	q := "INSERT INTO merchant_closures (id, merchant_id, start_at, end_at, reason) values (:id, :merchant_id, :start_at, :end_at, :reason);"
+
+	if _, err := r.db.NamedExec(q, &entity); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+func (r *scheduleRepository) DeleteClosure(merchantId string, closureId string) *localError.GlobalError {
+	res, err := r.db.Exec("DELETE FROM merchant_closures WHERE id = $1 AND merchant_id = $2", closureId, merchantId)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Closure data not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// Check whether every merchant is open at the given time,
+// including the closure that makes the merchant closed
+func (r *scheduleRepository) FindAvailability(IDs []string, at time.Time) ([]MerchantAvailability, *localError.GlobalError) {
+	availability := []MerchantAvailability{}
+
+	q := `
+		SELECT m.id, m.name, is_merchant_open(m.id, ?) as is_open,
+			c.end_at as closure_end_at, c.reason as closure_reason
+		FROM merchants m
+		LEFT JOIN LATERAL (
+			SELECT mc.end_at, mc.reason
+			FROM merchant_closures mc
+			WHERE mc.merchant_id = m.id AND mc.start_at <= ? AND mc.end_at > ?
+			ORDER BY mc.end_at DESC
+			LIMIT 1
+		) c ON true
+		WHERE m.id in (?)
+	`
+
+	query, args, err := sqlx.In(q, at, at, at, IDs)
+	if err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	query = r.db.Rebind(query)
+
+	if err := r.db.Select(&availability, query, args...); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return availability, nil
+}
@@ -0,0 +1,148 @@
+package merchant
+
+import (
+	localError "belimang/pkg/error"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (uc *merchantUsecase) FindOpeningHours(merchantId string) (*OpeningHoursResponse, *localError.GlobalError) {
+	merchant, err := uc.repo.FindMerchantById(merchantId)
+	if err != nil {
+		return nil, err
+	}
+
+	hours, err := uc.scheduleRepo.FindOpeningHours(merchantId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := FormatOpeningHoursResponse(*merchant, hours)
+
+	return &response, nil
+}
+
+func (uc *merchantUsecase) UpdateOpeningHours(merchantId string, req UpdateOpeningHoursDTO) (*OpeningHoursResponse, *localError.GlobalError) {
+	if !IsValidTimezone(req.Timezone) {
+		return nil, localError.ErrBadRequest("Timezone is not valid", fmt.Errorf("invalid timezone %s", req.Timezone))
+	}
+
+	hours := []OpeningHour{}
+	days := make(map[int]bool)
+
+	for _, v := range req.OpeningHours {
+		// Merchant only has one opening hours each day
+		if days[*v.DayOfWeek] {
+			message := fmt.Sprintf("Opening hours of day %d is duplicated", *v.DayOfWeek)
+			return nil, localError.ErrBadRequest(message, fmt.Errorf("duplicated day of week %d", *v.DayOfWeek))
+		}
+		days[*v.DayOfWeek] = true
+
+		hours = append(hours, OpeningHour{
+			MerchantID: merchantId,
+			DayOfWeek:  *v.DayOfWeek,
+			OpenTime:   v.Open,
+			CloseTime:  v.Close,
+		})
+	}
+
+	if err := uc.scheduleRepo.ReplaceOpeningHours(merchantId, req.Timezone, hours); err != nil {
+		return nil, err
+	}
+
+	return uc.FindOpeningHours(merchantId)
+}
+
+// List active and upcoming closures of the merchant
+func (uc *merchantUsecase) FindClosures(merchantId string) ([]ClosureResponse, *localError.GlobalError) {
+	if _, err := uc.repo.FindMerchantById(merchantId); err != nil {
+		return nil, err
+	}
+
+	closures, err := uc.scheduleRepo.FindClosures(merchantId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatClosureResponse(closures), nil
+}
+
+func (uc *merchantUsecase) CreateClosure(merchantId string, req CreateClosureDTO) (*ClosureResponse, *localError.GlobalError) {
+	if _, err := uc.repo.FindMerchantById(merchantId); err != nil {
+		return nil, err
+	}
+
+	closure := MerchantClosure{
+		ID:         uuid.NewString(),
+		MerchantID: merchantId,
+		StartAt:    req.StartAt,
+		EndAt:      req.EndAt,
+		Reason:     sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+	}
+
+	if err := uc.scheduleRepo.CreateClosure(closure); err != nil {
+		return nil, err
+	}
+
+	response := FormatClosureResponse([]MerchantClosure{closure})[0]
+
+	return &response, nil
+}
+
+func (uc *merchantUsecase) DeleteClosure(merchantId string, closureId string) *localError.GlobalError {
+	return uc.scheduleRepo.DeleteClosure(merchantId, closureId)
+}
+
+// Return error that describes every closed merchant at the given time
+func (uc *merchantUsecase) CheckMerchantsOpen(IDs []string, at time.Time) *localError.GlobalError {
+	availability, err := uc.scheduleRepo.FindAvailability(IDs, at)
+	if err != nil {
+		return err
+	}
+
+	var reasons []string
+	for _, v := range availability {
+		if !v.IsOpen {
+			reasons = append(reasons, closedReason(v))
+		}
+	}
+
+	if len(reasons) > 0 {
+		message := fmt.Sprintf("Merchant closed: %s", strings.Join(reasons, ", "))
+		return localError.ErrBadRequest(message, fmt.Errorf("%d merchant is closed", len(reasons)))
+	}
+
+	return nil
+}
+
+// Return the reason of every closed merchant at the given time by its ID
+func (uc *merchantUsecase) FindClosedMerchants(IDs []string, at time.Time) (map[string]string, *localError.GlobalError) {
+	availability, err := uc.scheduleRepo.FindAvailability(IDs, at)
+	if err != nil {
+		return nil, err
+	}
+
+	closed := make(map[string]string)
+	for _, v := range availability {
+		if !v.IsOpen {
+			closed[v.MerchantID] = closedReason(v)
+		}
+	}
+
+	return closed, nil
+}
+
+func closedReason(v MerchantAvailability) string {
+	switch {
+	case v.ClosureEndAt.Valid && v.ClosureReason.Valid:
+		return fmt.Sprintf("%s is closed until %s (%s)", v.Name, v.ClosureEndAt.Time.Format(time.RFC3339), v.ClosureReason.String)
+	case v.ClosureEndAt.Valid:
+		return fmt.Sprintf("%s is closed until %s", v.Name, v.ClosureEndAt.Time.Format(time.RFC3339))
+	default:
+		return fmt.Sprintf("%s is outside its opening hours", v.Name)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"belimang/pkg/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Check if the path param is a UUID.
+// ID that is not UUID will never exists, so it is rejected as not found
+// instead of reaching the database as an invalid query.
+func ValidateUUIDParam(param string, message string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, err := uuid.Parse(ctx.Param(param)); err != nil {
+			response.GenerateResponse(ctx, http.StatusNotFound, response.WithMessage(message))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,203 @@
+package promotion
+
+import (
+	"belimang/internal/merchant"
+	"database/sql"
+	"time"
+)
+
+type DiscountType string
+
+const (
+	PercentageDiscount DiscountType = "percentage"
+	FixedDiscount      DiscountType = "fixed"
+	FreeDelivery       DiscountType = "free_delivery"
+)
+
+// Promotion that can be applied to an order with its voucher code.
+// Scope is limited to the merchant or merchant category when it is set.
+type Promotion struct {
+	ID                string         `db:"id"`
+	Code              string         `db:"code"`
+	Description       string         `db:"description"`
+	DiscountType      DiscountType   `db:"discount_type"`
+	DiscountValue     int            `db:"discount_value"` // Percent for percentage discount, amount for fixed discount
+	MaxDiscount       sql.NullInt32  `db:"max_discount"`   // Cap of percentage discount
+	MinSpend          int            `db:"min_spend"`      // Minimum items price of the merchants in scope
+	MerchantID        sql.NullString `db:"merchant_id"`
+	MerchantCategory  sql.NullString `db:"merchant_category"`
+	UsageLimit        sql.NullInt32  `db:"usage_limit"`
+	UsageLimitPerUser sql.NullInt32  `db:"usage_limit_per_user"`
+	StartsAt          time.Time      `db:"starts_at"`
+	EndsAt            sql.NullTime   `db:"ends_at"`
+	DeletedAt         sql.NullTime   `db:"deleted_at"`
+	CreatedAt         time.Time      `db:"created_at"`
+}
+
+// Amount of placed orders that use the promotion, cancelled and failed orders are not counted
+type Usage struct {
+	Total  int `db:"total"`
+	ByUser int `db:"by_user"`
+}
+
+// Check if the promotion can be used at the given time
+func (p Promotion) IsActiveAt(at time.Time) bool {
+	if p.DeletedAt.Valid || at.Before(p.StartsAt) {
+		return false
+	}
+
+	return !p.EndsAt.Valid || at.Before(p.EndsAt.Time)
+}
+
+// Check if the promotion has not reached its global or per user usage limit
+func (p Promotion) IsUsable(usage Usage) bool {
+	if p.UsageLimit.Valid && usage.Total >= int(p.UsageLimit.Int32) {
+		return false
+	}
+
+	return !p.UsageLimitPerUser.Valid || usage.ByUser < int(p.UsageLimitPerUser.Int32)
+}
+
+// Items price of the merchants in the promotion scope
+func (p Promotion) EligibleSubtotal(cart Cart) int {
+	subtotal := 0
+
+	for _, m := range cart.Merchants {
+		if p.MerchantID.Valid && p.MerchantID.String != m.MerchantID {
+			continue
+		}
+
+		if p.MerchantCategory.Valid && p.MerchantCategory.String != string(m.Category) {
+			continue
+		}
+
+		subtotal += m.Subtotal
+	}
+
+	return subtotal
+}
+
+// Discount amount of the order, it never exceeds the discounted price
+func (p Promotion) DiscountOf(eligibleSubtotal int, deliveryFee int) int {
+	switch p.DiscountType {
+	case PercentageDiscount:
+		discount := eligibleSubtotal * p.DiscountValue / 100
+		if p.MaxDiscount.Valid && discount > int(p.MaxDiscount.Int32) {
+			discount = int(p.MaxDiscount.Int32)
+		}
+
+		return discount
+	case FixedDiscount:
+		return min(p.DiscountValue, eligibleSubtotal)
+	case FreeDelivery:
+		return deliveryFee
+	}
+
+	return 0
+}
+
+// Items price of a merchant in the order
+type CartMerchant struct {
+	MerchantID string
+	Category   merchant.MerchantCategories
+	Subtotal   int
+}
+
+// Order that the promotion is applied to
+type Cart struct {
+	UserID      string
+	Merchants   []CartMerchant
+	DeliveryFee int
+}
+
+// Discount of the applied promotion
+type Discount struct {
+	PromotionID string
+	Code        string
+	Amount      int
+}
+
+type CreatePromotionDTO struct {
+	Code              string                      `json:"code" binding:"required,alphanum,min=3,max=30"`
+	Description       string                      `json:"description" binding:"max=200"`
+	DiscountType      DiscountType                `json:"discountType" binding:"required,oneof=percentage fixed free_delivery"`
+	DiscountValue     int                         `json:"discountValue" binding:"min=0"`
+	MaxDiscount       *int                        `json:"maxDiscount" binding:"omitempty,min=1"`
+	MinSpend          int                         `json:"minSpend" binding:"min=0"`
+	MerchantID        string                      `json:"merchantId" binding:"omitempty,uuid"`
+	MerchantCategory  merchant.MerchantCategories `json:"merchantCategory" binding:"omitempty,oneof=SmallRestaurant MediumRestaurant LargeRestaurant MerchandiseRestaurant BoothKiosk ConvenienceStore"`
+	UsageLimit        *int                        `json:"usageLimit" binding:"omitempty,min=1"`
+	UsageLimitPerUser *int                        `json:"usageLimitPerUser" binding:"omitempty,min=1"`
+	StartsAt          time.Time                   `json:"startsAt" binding:"required"`
+	EndsAt            *time.Time                  `json:"endsAt"`
+}
+
+type GetPromotionQueryParams struct {
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+	Code   string `form:"code"`
+}
+
+type PromotionResponse struct {
+	PromotionID       string                      `json:"promotionId"`
+	Code              string                      `json:"code"`
+	Description       string                      `json:"description"`
+	DiscountType      DiscountType                `json:"discountType"`
+	DiscountValue     int                         `json:"discountValue"`
+	MaxDiscount       *int                        `json:"maxDiscount"`
+	MinSpend          int                         `json:"minSpend"`
+	MerchantID        string                      `json:"merchantId,omitempty"`
+	MerchantCategory  merchant.MerchantCategories `json:"merchantCategory,omitempty"`
+	UsageLimit        *int                        `json:"usageLimit"`
+	UsageLimitPerUser *int                        `json:"usageLimitPerUser"`
+	StartsAt          string                      `json:"startsAt"`
+	EndsAt            *string                     `json:"endsAt"`
+	CreatedAt         string                      `json:"createdAt"`
+}
+
+type PromotionResponseAndMeta struct {
+	Data []PromotionResponse `json:"data"`
+	Meta merchant.Meta       `json:"meta"`
+}
+
+// Pointer of nullable integer, nil means no limit
+func nullableInt(value sql.NullInt32) *int {
+	if !value.Valid {
+		return nil
+	}
+
+	v := int(value.Int32)
+
+	return &v
+}
+
+func FormatPromotionResponse(promotions []Promotion) []PromotionResponse {
+	response := []PromotionResponse{}
+
+	for _, p := range promotions {
+		row := PromotionResponse{
+			PromotionID:       p.ID,
+			Code:              p.Code,
+			Description:       p.Description,
+			DiscountType:      p.DiscountType,
+			DiscountValue:     p.DiscountValue,
+			MaxDiscount:       nullableInt(p.MaxDiscount),
+			MinSpend:          p.MinSpend,
+			MerchantID:        p.MerchantID.String,
+			MerchantCategory:  merchant.MerchantCategories(p.MerchantCategory.String),
+			UsageLimit:        nullableInt(p.UsageLimit),
+			UsageLimitPerUser: nullableInt(p.UsageLimitPerUser),
+			StartsAt:          p.StartsAt.Format(time.RFC3339),
+			CreatedAt:         p.CreatedAt.Format(time.RFC3339),
+		}
+
+		if p.EndsAt.Valid {
+			endsAt := p.EndsAt.Time.Format(time.RFC3339)
+			row.EndsAt = &endsAt
+		}
+
+		response = append(response, row)
+	}
+
+	return response
+}
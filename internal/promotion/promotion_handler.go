@@ -0,0 +1,82 @@
+package promotion
+
+import (
+	"belimang/internal/middleware"
+	"belimang/internal/user"
+	"belimang/pkg/response"
+	"belimang/pkg/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type promotionHandler struct {
+	uc IPromotionUsecase
+}
+
+func NewPromotionHandler(uc IPromotionUsecase) *promotionHandler {
+	return &promotionHandler{
+		uc: uc,
+	}
+}
+
+func (h *promotionHandler) Router(r *gin.RouterGroup) {
+	// Promotion is managed by admin, user applies it with the voucher code on estimate
+	adminGroup := r.Group("admin/promotions", middleware.UseJwtAuth, middleware.HasRoles(string(user.ADMIN)))
+
+	adminGroup.POST("", h.CreatePromotion)
+	adminGroup.GET("", h.FindAllPromotions)
+	adminGroup.DELETE("/:promotionId", middleware.ValidateUUIDParam("promotionId", "Promotion data not found"), h.DeletePromotion)
+}
+
+func (h *promotionHandler) CreatePromotion(c *gin.Context) {
+	var request CreatePromotionDTO
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	resp, err := h.uc.CreatePromotion(request)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusCreated, response.WithData(*resp))
+}
+
+func (h *promotionHandler) FindAllPromotions(c *gin.Context) {
+	var query GetPromotionQueryParams
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	promotions, err := h.uc.FindAllPromotions(query)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusOK, response.WithMessage("Promotion fetched successfully!"), response.WithData(promotions))
+}
+
+func (h *promotionHandler) DeletePromotion(c *gin.Context) {
+	promotionId := c.Param("promotionId")
+
+	if err := h.uc.DeletePromotion(promotionId); err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponse(c, http.StatusOK, response.WithMessage("Promotion deleted successfully!"))
+}
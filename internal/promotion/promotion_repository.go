@@ -0,0 +1,159 @@
+package promotion
+
+import (
+	localError "belimang/pkg/error"
+	"belimang/pkg/sqlbuilder"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Postgres error code of unique index violation
+const uniqueViolation = "23505"
+
+type IPromotionRepository interface {
+	CreatePromotion(entity *Promotion) *localError.GlobalError
+	FindAllPromotions(params GetPromotionQueryParams) ([]Promotion, int, *localError.GlobalError)
+	FindPromotionByCode(code string) (*Promotion, *localError.GlobalError)
+	DeletePromotion(id string) *localError.GlobalError
+	FindUsage(promotionId string, userId string) (Usage, *localError.GlobalError)
+}
+
+type promotionRepository struct {
+	db *sqlx.DB
+}
+
+func NewPromotionRepository(db *sqlx.DB) IPromotionRepository {
+	return &promotionRepository{
+		db: db,
+	}
+}
+
+func (r *promotionRepository) CreatePromotion(entity *Promotion) *localError.GlobalError {
+	q := `INSERT INTO promotions
+		(code, description, discount_type, discount_value, max_discount, min_spend, merchant_id, merchant_category, usage_limit, usage_limit_per_user, starts_at, ends_at)
+		VALUES
+		(:code, :description, :discount_type, :discount_value, :max_discount, :min_spend, :merchant_id, :merchant_category, :usage_limit, :usage_limit_per_user, :starts_at, :ends_at)
+		RETURNING id, created_at`
+
+	rows, err := r.db.NamedQuery(q, entity)
+	if err != nil {
+		return createPromotionError(err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&entity.ID, &entity.CreatedAt); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return createPromotionError(err)
+	}
+
+	return nil
+}
+
+// Code of active promotion is unique, so concurrent create of the same code is a conflict
+func createPromotionError(err error) *localError.GlobalError {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return localError.ErrConflict("Promotion code already exists", err)
+	}
+
+	return localError.ErrInternalServer(err.Error(), err)
+}
+
+// FindAllPromotions list promotions that are not deleted with the total of promotion that matches the filter
+func (r *promotionRepository) FindAllPromotions(params GetPromotionQueryParams) ([]Promotion, int, *localError.GlobalError) {
+	promotions := []Promotion{}
+
+	query := sqlbuilder.New("SELECT * FROM promotions")
+	query.Where("deleted_at IS NULL")
+
+	if params.Code != "" {
+		query.Where("code ILIKE ?", sqlbuilder.Contains(params.Code))
+	}
+
+	var total int
+
+	countQ, countArgs := query.Count()
+	if err := r.db.Get(&total, countQ, countArgs...); err != nil {
+		return promotions, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	query.OrderBy("created_at", sqlbuilder.Desc).
+		OrderBy("id", sqlbuilder.Desc).
+		Paginate(params.Limit, params.Offset)
+
+	q, args := query.Build()
+
+	if err := r.db.Select(&promotions, q, args...); err != nil {
+		return promotions, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return promotions, total, nil
+}
+
+// Find promotion that is not deleted by its case insensitive code
+func (r *promotionRepository) FindPromotionByCode(code string) (*Promotion, *localError.GlobalError) {
+	promotion := Promotion{}
+
+	q := "SELECT * FROM promotions WHERE UPPER(code) = $1 AND deleted_at IS NULL"
+
+	if err := r.db.Get(&promotion, q, strings.ToUpper(code)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, localError.ErrNotFound("Voucher code not found", err)
+		}
+
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &promotion, nil
+}
+
+// Soft delete the promotion, orders that already use it keep the discount
+func (r *promotionRepository) DeletePromotion(id string) *localError.GlobalError {
+	res, err := r.db.Exec("UPDATE promotions SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return localError.ErrNotFound("Promotion data not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (r *promotionRepository) FindUsage(promotionId string, userId string) (Usage, *localError.GlobalError) {
+	usage, err := CountUsage(r.db, promotionId, userId)
+	if err != nil {
+		return usage, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return usage, nil
+}
+
+// CountUsage count placed orders that use the promotion, globally and by the user.
+// It accepts a transaction, so the usage can be checked while the promotion is locked.
+func CountUsage(q sqlx.Queryer, promotionId string, userId string) (Usage, error) {
+	usage := Usage{}
+
+	query := `
+		SELECT
+			COUNT(*) as total,
+			COUNT(*) FILTER (WHERE oe.user_id = $2) as by_user
+		FROM orders o
+		INNER JOIN order_estimation oe ON o.order_estimation_id = oe.id
+		WHERE oe.promotion_id = $1 AND o.status NOT IN ('cancelled', 'failed')
+	`
+
+	err := sqlx.Get(q, &usage, query, promotionId, userId)
+
+	return usage, err
+}
@@ -0,0 +1,144 @@
+package promotion
+
+import (
+	"belimang/internal/merchant"
+	localError "belimang/pkg/error"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type IPromotionUsecase interface {
+	CreatePromotion(req CreatePromotionDTO) (*PromotionResponse, *localError.GlobalError)
+	FindAllPromotions(params GetPromotionQueryParams) (PromotionResponseAndMeta, *localError.GlobalError)
+	DeletePromotion(id string) *localError.GlobalError
+	Apply(code string, cart Cart, at time.Time) (*Discount, *localError.GlobalError)
+}
+
+type promotionUsecase struct {
+	repo       IPromotionRepository
+	merchantUc merchant.IMerchantUsecase
+}
+
+func NewPromotionUsecase(repo IPromotionRepository, mUc merchant.IMerchantUsecase) IPromotionUsecase {
+	return &promotionUsecase{
+		repo:       repo,
+		merchantUc: mUc,
+	}
+}
+
+func (uc *promotionUsecase) CreatePromotion(req CreatePromotionDTO) (*PromotionResponse, *localError.GlobalError) {
+	if req.DiscountType == PercentageDiscount && (req.DiscountValue < 1 || req.DiscountValue > 100) {
+		return nil, localError.ErrBadRequest("discountValue should be between 1 and 100 for percentage discount", errors.New("invalid percentage"))
+	}
+
+	if req.DiscountType == FixedDiscount && req.DiscountValue < 1 {
+		return nil, localError.ErrBadRequest("discountValue should be greater than 0 for fixed discount", errors.New("invalid fixed discount"))
+	}
+
+	if req.EndsAt != nil && !req.EndsAt.After(req.StartsAt) {
+		return nil, localError.ErrBadRequest("endsAt should be after startsAt", errors.New("invalid validity window"))
+	}
+
+	if req.MerchantID != "" {
+		if _, err := uc.merchantUc.FindMerchantById(req.MerchantID); err != nil {
+			return nil, err
+		}
+	}
+
+	promotion := Promotion{
+		Code:             strings.ToUpper(req.Code),
+		Description:      req.Description,
+		DiscountType:     req.DiscountType,
+		DiscountValue:    req.DiscountValue,
+		MinSpend:         req.MinSpend,
+		MerchantID:       sql.NullString{String: req.MerchantID, Valid: req.MerchantID != ""},
+		MerchantCategory: sql.NullString{String: string(req.MerchantCategory), Valid: req.MerchantCategory != ""},
+		StartsAt:         req.StartsAt,
+	}
+
+	// Free delivery discount is the delivery fee
+	if promotion.DiscountType == FreeDelivery {
+		promotion.DiscountValue = 0
+	}
+
+	if req.MaxDiscount != nil {
+		promotion.MaxDiscount = sql.NullInt32{Int32: int32(*req.MaxDiscount), Valid: true}
+	}
+
+	if req.UsageLimit != nil {
+		promotion.UsageLimit = sql.NullInt32{Int32: int32(*req.UsageLimit), Valid: true}
+	}
+
+	if req.UsageLimitPerUser != nil {
+		promotion.UsageLimitPerUser = sql.NullInt32{Int32: int32(*req.UsageLimitPerUser), Valid: true}
+	}
+
+	if req.EndsAt != nil {
+		promotion.EndsAt = sql.NullTime{Time: *req.EndsAt, Valid: true}
+	}
+
+	if err := uc.repo.CreatePromotion(&promotion); err != nil {
+		return nil, err
+	}
+
+	response := FormatPromotionResponse([]Promotion{promotion})[0]
+
+	return &response, nil
+}
+
+func (uc *promotionUsecase) FindAllPromotions(params GetPromotionQueryParams) (PromotionResponseAndMeta, *localError.GlobalError) {
+	promotions, total, err := uc.repo.FindAllPromotions(params)
+	if err != nil {
+		return PromotionResponseAndMeta{}, err
+	}
+
+	return PromotionResponseAndMeta{
+		Data: FormatPromotionResponse(promotions),
+		Meta: merchant.NewMeta(params.Limit, params.Offset, total),
+	}, nil
+}
+
+func (uc *promotionUsecase) DeletePromotion(id string) *localError.GlobalError {
+	return uc.repo.DeletePromotion(id)
+}
+
+// Apply the voucher code to the order.
+// The promotion should be active, not used up, and the merchants in its scope should reach the minimum spend.
+func (uc *promotionUsecase) Apply(code string, cart Cart, at time.Time) (*Discount, *localError.GlobalError) {
+	promotion, err := uc.repo.FindPromotionByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !promotion.IsActiveAt(at) {
+		return nil, localError.ErrBadRequest("Voucher is not active", fmt.Errorf("promotion %s is not active", promotion.ID))
+	}
+
+	eligible := promotion.EligibleSubtotal(cart)
+	if eligible == 0 {
+		return nil, localError.ErrBadRequest("Voucher can not be used for the ordered merchants", fmt.Errorf("promotion %s has no eligible merchant", promotion.ID))
+	}
+
+	if eligible < promotion.MinSpend {
+		message := fmt.Sprintf("Minimum spend of the voucher is %d", promotion.MinSpend)
+		return nil, localError.ErrBadRequest(message, fmt.Errorf("promotion %s minimum spend is not reached", promotion.ID))
+	}
+
+	usage, err := uc.repo.FindUsage(promotion.ID, cart.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !promotion.IsUsable(usage) {
+		return nil, localError.ErrBadRequest("Voucher usage limit has been reached", fmt.Errorf("promotion %s is used up", promotion.ID))
+	}
+
+	return &Discount{
+		PromotionID: promotion.ID,
+		Code:        promotion.Code,
+		Amount:      promotion.DiscountOf(eligible, cart.DeliveryFee),
+	}, nil
+}
@@ -0,0 +1,209 @@
+package purchase
+
+import (
+	"belimang/pkg/distances"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type VehicleProfile string
+
+const (
+	Motorbike VehicleProfile = "motorbike"
+	Bicycle   VehicleProfile = "bicycle"
+	Car       VehicleProfile = "car"
+)
+
+// Average courier speed of each vehicle in kph
+var vehicleSpeeds = map[VehicleProfile]float64{
+	Motorbike: 40,
+	Bicycle:   15,
+	Car:       30,
+}
+
+// Speed multiplier for a time range in a day, e.g. 0.6 during rush hour.
+// Range is [StartHour, EndHour) in the ETA timezone.
+type SpeedMultiplier struct {
+	StartHour  int
+	EndHour    int
+	Multiplier float64
+}
+
+type EtaConfig struct {
+	Vehicle                   VehicleProfile
+	SpeedKph                  float64
+	SpeedMultipliers          []SpeedMultiplier
+	StopHandlingMinutes       float64 // Time spent on every stop to pick up or drop the order
+	DefaultPreparationMinutes float64 // Used when merchant has no preparation time
+	Location                  *time.Location
+}
+
+// Delivery time of a route
+type EtaResult struct {
+	LegMinutes   []float64 // Travel time of every leg of the route
+	TotalMinutes float64   // Time until the order arrives at user location
+}
+
+type IEtaModel interface {
+	// Estimate delivery time of the route that departs at the given time.
+	// preparation is the food preparation time of each merchant ID in minutes.
+	Estimate(route distances.Route, preparation map[string]int, departure time.Time) EtaResult
+}
+
+type etaModel struct {
+	config EtaConfig
+}
+
+func NewEtaModel(config EtaConfig) IEtaModel {
+	if config.SpeedKph <= 0 {
+		config.SpeedKph = vehicleSpeeds[Motorbike]
+	}
+
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+
+	return &etaModel{
+		config: config,
+	}
+}
+
+// Load ETA config from environment variable
+//
+//	DELIVERY_VEHICLE                 motorbike, bicycle or car
+//	DELIVERY_SPEED_KPH               override the vehicle speed
+//	ETA_SPEED_MULTIPLIERS            comma separated "startHour-endHour:multiplier", e.g. "7-9:0.6,17-19:0.6"
+//	ETA_STOP_HANDLING_MINUTES        time spent on every stop
+//	ETA_DEFAULT_PREPARATION_MINUTES  preparation time of merchant that doesn't have one
+//	ETA_TIMEZONE                     timezone of the speed multipliers hour
+func LoadEtaConfig() EtaConfig {
+	config := EtaConfig{
+		Vehicle:  Motorbike,
+		Location: time.UTC,
+	}
+
+	if vehicle := VehicleProfile(os.Getenv("DELIVERY_VEHICLE")); vehicle != "" {
+		if _, exists := vehicleSpeeds[vehicle]; exists {
+			config.Vehicle = vehicle
+		} else {
+			log.Printf("unknown delivery vehicle %s, using %s", vehicle, config.Vehicle)
+		}
+	}
+	config.SpeedKph = vehicleSpeeds[config.Vehicle]
+
+	if speed, err := strconv.ParseFloat(os.Getenv("DELIVERY_SPEED_KPH"), 64); err == nil && speed > 0 {
+		config.SpeedKph = speed
+	}
+
+	if minutes, err := strconv.ParseFloat(os.Getenv("ETA_STOP_HANDLING_MINUTES"), 64); err == nil && minutes >= 0 {
+		config.StopHandlingMinutes = minutes
+	}
+
+	if minutes, err := strconv.ParseFloat(os.Getenv("ETA_DEFAULT_PREPARATION_MINUTES"), 64); err == nil && minutes >= 0 {
+		config.DefaultPreparationMinutes = minutes
+	}
+
+	if tz := os.Getenv("ETA_TIMEZONE"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("invalid ETA timezone %s: %s", tz, err.Error())
+		} else {
+			config.Location = location
+		}
+	}
+
+	config.SpeedMultipliers = parseSpeedMultipliers(os.Getenv("ETA_SPEED_MULTIPLIERS"))
+
+	return config
+}
+
+// Parse "7-9:0.6,17-19:0.6" into speed multipliers, invalid value is skipped
+func parseSpeedMultipliers(raw string) []SpeedMultiplier {
+	multipliers := []SpeedMultiplier{}
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		hours, value, found := strings.Cut(part, ":")
+		startRaw, endRaw, foundRange := strings.Cut(hours, "-")
+		if !found || !foundRange {
+			log.Printf("invalid speed multiplier %s", part)
+			continue
+		}
+
+		start, errStart := strconv.Atoi(startRaw)
+		end, errEnd := strconv.Atoi(endRaw)
+		multiplier, errMultiplier := strconv.ParseFloat(value, 64)
+
+		if errStart != nil || errEnd != nil || errMultiplier != nil || multiplier <= 0 || start < 0 || end > 24 || start >= end {
+			log.Printf("invalid speed multiplier %s", part)
+			continue
+		}
+
+		multipliers = append(multipliers, SpeedMultiplier{
+			StartHour:  start,
+			EndHour:    end,
+			Multiplier: multiplier,
+		})
+	}
+
+	return multipliers
+}
+
+// Speed of the courier at the given time in kph
+func (m *etaModel) speedAt(t time.Time) float64 {
+	hour := t.In(m.config.Location).Hour()
+
+	for _, sm := range m.config.SpeedMultipliers {
+		if hour >= sm.StartHour && hour < sm.EndHour {
+			return m.config.SpeedKph * sm.Multiplier
+		}
+	}
+
+	return m.config.SpeedKph
+}
+
+// Estimate the delivery time.
+// Courier waits at every merchant until the order is prepared, then spends handling time on every stop.
+// Leg speed follows the time of day when the courier departs.
+func (m *etaModel) Estimate(route distances.Route, preparation map[string]int, departure time.Time) EtaResult {
+	result := EtaResult{
+		LegMinutes: []float64{},
+	}
+
+	elapsed := 0.0
+
+	for i, leg := range route.Legs {
+		// Wait for the order of the merchant to be ready
+		ready := m.config.DefaultPreparationMinutes
+		if minutes, exists := preparation[route.Stops[i].ID]; exists {
+			ready = float64(minutes)
+		}
+
+		if elapsed < ready {
+			elapsed = ready
+		}
+		elapsed += m.config.StopHandlingMinutes
+
+		at := departure.Add(time.Duration(elapsed * float64(time.Minute)))
+		legMinutes := leg / m.speedAt(at) * 60
+
+		result.LegMinutes = append(result.LegMinutes, legMinutes)
+		elapsed += legMinutes
+	}
+
+	// Drop the order at user location
+	if len(route.Legs) > 0 {
+		elapsed += m.config.StopHandlingMinutes
+	}
+
+	result.TotalMinutes = elapsed
+
+	return result
+}
@@ -0,0 +1,41 @@
+package purchase
+
+import (
+	"bytes"
+	"database/sql"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header used by client to make a retry safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Stored request & response of an idempotency key.
+// Response is empty while the first request is still processed.
+type IdempotencyKey struct {
+	UserID       string        `db:"user_id"`
+	Endpoint     string        `db:"endpoint"`
+	Key          string        `db:"key"`
+	RequestHash  string        `db:"request_hash"`
+	ResponseCode sql.NullInt32 `db:"response_code"`
+	ResponseBody []byte        `db:"response_body"`
+	CreatedAt    time.Time     `db:"created_at"`
+}
+
+// Response writer that keep a copy of the response body,
+// so the response can be stored for the next retry
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w idempotencyResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
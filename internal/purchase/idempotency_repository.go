@@ -0,0 +1,110 @@
+package purchase
+
+import (
+	localError "belimang/pkg/error"
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Default lifetime of idempotency key, after that the key can be reused
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// Get idempotency key lifetime from environment variable.
+// IDEMPOTENCY_KEY_TTL_HOURS should be a valid positive integer, otherwise default lifetime is used.
+func getIdempotencyKeyTTL() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"))
+	if err != nil || hours <= 0 {
+		return defaultIdempotencyKeyTTL
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+type IIdempotencyRepository interface {
+	Reserve(entity IdempotencyKey) (*IdempotencyKey, bool, *localError.GlobalError)
+	SaveResponse(entity IdempotencyKey) *localError.GlobalError
+	Release(entity IdempotencyKey) *localError.GlobalError
+}
+
+type idempotencyRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) IIdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+// Reserve store new idempotency key.
+// If the key is already used, the stored key is returned with reserved = false.
+// Expired key is replaced by the new request.
+func (repo *idempotencyRepository) Reserve(entity IdempotencyKey) (*IdempotencyKey, bool, *localError.GlobalError) {
+	stored := IdempotencyKey{}
+	expiredBefore := time.Now().Add(-getIdempotencyKeyTTL())
+
+	q := `
+		INSERT INTO idempotency_keys (user_id, endpoint, key, request_hash)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, endpoint, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, response_code = NULL, response_body = NULL, created_at = CURRENT_TIMESTAMP
+		WHERE idempotency_keys.created_at < $5
+		RETURNING *
+	`
+
+	err := repo.db.Get(&stored, q, entity.UserID, entity.Endpoint, entity.Key, entity.RequestHash, expiredBefore)
+	if err == nil {
+		return &stored, true, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	// Key is already used by previous request
+	findQ := "SELECT * FROM idempotency_keys WHERE user_id = $1 AND endpoint = $2 AND key = $3"
+
+	err = repo.db.Get(&stored, findQ, entity.UserID, entity.Endpoint, entity.Key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Key is released in the middle of the process, let the client retry
+			return nil, false, localError.ErrConflict("Request with the same idempotency key is being processed", err)
+		}
+
+		return nil, false, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &stored, false, nil
+}
+
+// SaveResponse store the response of the reserved key
+func (repo *idempotencyRepository) SaveResponse(entity IdempotencyKey) *localError.GlobalError {
+	q := `
+		UPDATE idempotency_keys SET response_code = $1, response_body = $2
+		WHERE user_id = $3 AND endpoint = $4 AND key = $5
+	`
+
+	_, err := repo.db.Exec(q, entity.ResponseCode, entity.ResponseBody, entity.UserID, entity.Endpoint, entity.Key)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Release remove the reserved key, so the request can be retried
+func (repo *idempotencyRepository) Release(entity IdempotencyKey) *localError.GlobalError {
+	q := "DELETE FROM idempotency_keys WHERE user_id = $1 AND endpoint = $2 AND key = $3 AND response_code IS NULL"
+
+	_, err := repo.db.Exec(q, entity.UserID, entity.Endpoint, entity.Key)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
@@ -2,27 +2,135 @@ package purchase
 
 import (
 	merchantModule "belimang/internal/merchant"
+	"belimang/pkg/distances"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 )
 
-const (
-	DeliveryVelocity int = 40 // In kph
-)
-
 type OrderEstimation struct {
-	ID            string  `json:"calculatedEstimateId" db:"id"`
-	UserID        string  `json:"-" db:"user_id"`
-	UserLat       float64 `json:"-" db:"user_location_lat"`
-	UserLong      float64 `json:"-" db:"user_location_long"`
-	Price         int     `json:"totalPrice" db:"total_price"`
-	EstimatedTime int     `json:"estimatedDeliveryTimeInMinutes" db:"estimated_delivery_time"`
+	ID            string         `json:"calculatedEstimateId" db:"id"`
+	UserID        string         `json:"-" db:"user_id"`
+	UserLat       float64        `json:"-" db:"user_location_lat"`
+	UserLong      float64        `json:"-" db:"user_location_long"`
+	Price         int            `json:"totalPrice" db:"total_price"` // Items price with every fee and tax
+	EstimatedTime int            `json:"estimatedDeliveryTimeInMinutes" db:"estimated_delivery_time"`
+	CreatedAt     time.Time      `json:"-" db:"created_at"`
+	ExpiresAt     time.Time      `json:"-" db:"expires_at"`
+	ConsumedAt    sql.NullTime   `json:"-" db:"consumed_at"`
+	ItemsPrice    int            `json:"-" db:"items_price"`
+	DeliveryFee   int            `json:"-" db:"delivery_fee"`
+	SmallOrderFee int            `json:"-" db:"small_order_fee"`
+	ServiceFee    int            `json:"-" db:"service_fee"`
+	Tax           int            `json:"-" db:"tax"`
+	PromotionID   sql.NullString `json:"-" db:"promotion_id"`
+	VoucherCode   sql.NullString `json:"-" db:"voucher_code"`
+	Discount      int            `json:"-" db:"discount"`
+}
+
+type PriceBreakdownResponse struct {
+	ItemsPrice    int    `json:"itemsPrice"`
+	DeliveryFee   int    `json:"deliveryFee"`
+	SmallOrderFee int    `json:"smallOrderFee"`
+	ServiceFee    int    `json:"serviceFee"`
+	Discount      int    `json:"discount"`
+	VoucherCode   string `json:"voucherCode,omitempty"`
+	Tax           int    `json:"tax"`
+	TotalPrice    int    `json:"totalPrice"`
+}
+
+func FormatPriceBreakdownResponse(estimation *OrderEstimation) PriceBreakdownResponse {
+	return PriceBreakdownResponse{
+		ItemsPrice:    estimation.ItemsPrice,
+		DeliveryFee:   estimation.DeliveryFee,
+		SmallOrderFee: estimation.SmallOrderFee,
+		ServiceFee:    estimation.ServiceFee,
+		Discount:      estimation.Discount,
+		VoucherCode:   estimation.VoucherCode.String,
+		Tax:           estimation.Tax,
+		TotalPrice:    estimation.Price,
+	}
 }
 
+// Ordered item of an estimation.
+// Merchant and item data is a snapshot taken when the estimation is created,
+// so changes on merchant or item won't change the order history.
 type OrderEstimationDetail struct {
-	OrderEstimationID string `db:"order_estimation_id"`
-	ItemID            string `db:"item_id"`
-	Quantity          int    `db:"quantity"`
+	OrderEstimationID    string                            `db:"order_estimation_id"`
+	ItemID               string                            `db:"item_id"`
+	Quantity             int                               `db:"quantity"`
+	MerchantID           string                            `db:"merchant_id"`
+	MerchantName         string                            `db:"merchant_name"`
+	MerchantLocationLat  float64                           `db:"merchant_location_lat"`
+	MerchantLocationLong float64                           `db:"merchant_location_long"`
+	ItemName             string                            `db:"item_name"`
+	ItemPrice            int                               `db:"item_price"`
+	Options              OrderItemOptions                  `db:"options"`
+	OptionsPrice         int                               `db:"options_price"` // Sum of selected option price delta of one item
+	MerchantCategory     merchantModule.MerchantCategories `db:"merchant_category"`
+	MerchantImageUrl     string                            `db:"merchant_image_url"`
+	MerchantCreatedAt    time.Time                         `db:"merchant_created_at"`
+	ProductCategory      merchantModule.ProductCategories  `db:"item_product_category"`
+	ItemImageUrl         string                            `db:"item_image_url"`
+	ItemCreatedAt        time.Time                         `db:"item_created_at"`
+}
+
+// Unit price of the ordered item including its selected options
+func (d OrderEstimationDetail) UnitPrice() int {
+	return d.ItemPrice + d.OptionsPrice
+}
+
+// Snapshot of selected option of the ordered item
+type OrderItemOption struct {
+	OptionGroupID   string `json:"optionGroupId"`
+	OptionGroupName string `json:"optionGroupName"`
+	OptionID        string `json:"optionId"`
+	Name            string `json:"name"`
+	PriceDelta      int    `json:"priceDelta"`
+}
+
+// Selected options stored as JSON column
+type OrderItemOptions []OrderItemOption
+
+func (o OrderItemOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(o)
+}
+
+func (o *OrderItemOptions) Scan(src any) error {
+	var raw []byte
+
+	switch v := src.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		*o = OrderItemOptions{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for order item options", src)
+	}
+
+	return json.Unmarshal(raw, o)
+}
+
+// Merchant visited in an estimation.
+// Leg is the route from this merchant to the next stop (next merchant or user location).
+type OrderEstimationMerchant struct {
+	OrderEstimationID string  `db:"order_estimation_id"`
+	MerchantID        string  `db:"merchant_id"`
+	IsStartingPoint   bool    `db:"is_starting_point"`
+	VisitOrder        int     `db:"visit_order"`
+	LegDistance       float64 `db:"leg_distance"` // In km
+	LegTime           int     `db:"leg_time"`     // In minutes
+	SmallOrderFee     int     `db:"small_order_fee"`
 }
 
 type UserLocation struct {
@@ -31,8 +139,9 @@ type UserLocation struct {
 }
 
 type Item struct {
-	ItemID   string `json:"itemId" binding:"required,uuid"`
-	Quantity int    `json:"quantity" binding:"required"`
+	ItemID    string   `json:"itemId" binding:"required,uuid"`
+	Quantity  int      `json:"quantity" binding:"required,min=1"`
+	OptionIDs []string `json:"optionIds" binding:"omitempty,dive,uuid"`
 }
 
 // Order estimation
@@ -42,6 +151,133 @@ type Order struct {
 	Items           []Item `json:"items" binding:"required,dive"`
 }
 
+type OrderStatus string
+
+const (
+	OrderPlaced    OrderStatus = "placed"
+	OrderAccepted  OrderStatus = "accepted"
+	OrderPreparing OrderStatus = "preparing"
+	OrderPickedUp  OrderStatus = "picked_up"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderFailed    OrderStatus = "failed"
+)
+
+// Allowed next status for each order status.
+// Status that is not listed here is a final status and cannot be changed anymore.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPlaced:    {OrderAccepted, OrderCancelled, OrderFailed},
+	OrderAccepted:  {OrderPreparing, OrderCancelled, OrderFailed},
+	OrderPreparing: {OrderPickedUp, OrderFailed},
+	OrderPickedUp:  {OrderDelivered, OrderFailed},
+}
+
+// Check if the order can be moved from current status to the next status
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+type StockReservationStatus string
+
+const (
+	StockReserved  StockReservationStatus = "reserved"
+	StockCommitted StockReservationStatus = "committed"
+	StockReleased  StockReservationStatus = "released"
+)
+
+// Item that does not have enough stock for the estimation
+type InsufficientStockError struct {
+	ItemID   string
+	ItemName string
+	Quantity int
+}
+
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("item %s stock is less than %d", e.ItemID, e.Quantity)
+}
+
+// Order row stored in orders table
+type PlacedOrder struct {
+	ID                string      `db:"id"`
+	OrderEstimationID string      `db:"order_estimation_id"`
+	UserID            string      `db:"user_id"`
+	Status            OrderStatus `db:"status"`
+	CreatedAt         time.Time   `db:"created_at"`
+	UpdatedAt         time.Time   `db:"updated_at"`
+}
+
+type UpdateOrderStatusDTO struct {
+	Status OrderStatus `json:"status" binding:"required,oneof=accepted preparing picked_up delivered cancelled failed"`
+	Note   string      `json:"note" binding:"max=255"`
+}
+
+// Status that the merchant sets while it prepares the order,
+// delivery and cancellation are not handled by the merchant
+type UpdateMerchantOrderStatusDTO struct {
+	Status OrderStatus `json:"status" binding:"required,oneof=accepted preparing picked_up"`
+	Note   string      `json:"note" binding:"max=255"`
+}
+
+type OrderStatusResponse struct {
+	OrderId   string          `json:"orderId"`
+	Status    OrderStatus     `json:"status"`
+	UpdatedAt string          `json:"updatedAt"`
+	Refund    *RefundResponse `json:"refund,omitempty"`
+}
+
+type CancelOrderDTO struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundProcessed RefundStatus = "processed"
+)
+
+// Refund of cancelled order.
+// Amount is taken from total price of the order estimation.
+type Refund struct {
+	ID          string         `db:"id"`
+	OrderID     string         `db:"order_id"`
+	Amount      int            `db:"amount"`
+	Reason      sql.NullString `db:"reason"`
+	Status      RefundStatus   `db:"status"`
+	RequestedBy sql.NullString `db:"requested_by"`
+	ProcessedBy sql.NullString `db:"processed_by"`
+	ProcessedAt sql.NullTime   `db:"processed_at"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+type RefundResponse struct {
+	RefundId  string       `json:"refundId"`
+	Amount    int          `json:"amount"`
+	Reason    string       `json:"reason"`
+	Status    RefundStatus `json:"status"`
+	CreatedAt string       `json:"createdAt"`
+}
+
+func FormatRefundResponse(refund *Refund) *RefundResponse {
+	if refund == nil {
+		return nil
+	}
+
+	return &RefundResponse{
+		RefundId:  refund.ID,
+		Amount:    refund.Amount,
+		Reason:    refund.Reason.String,
+		Status:    refund.Status,
+		CreatedAt: refund.CreatedAt.Format(time.RFC3339),
+	}
+}
+
 // Order that has been placed / confirmed
 type ActualOrder struct {
 	OrderId           string `json:"orderId"`
@@ -52,12 +288,27 @@ type Request struct {
 	UserId       string
 	UserLocation UserLocation `json:"userLocation" binding:"required"`
 	Orders       []Order      `json:"orders" binding:"required,dive"`
+	VoucherCode  string       `json:"voucherCode" binding:"max=30"`
 }
 
 type OrderEstimationResponse struct {
-	TotalPrice                     int    `json:"totalPrice"`
-	EstimatedDeliveryTimeInMinutes int    `json:"estimatedDeliveryTimeInMinutes"`
-	CalculatedEstimateID           string `json:"calculatedEstimateId"`
+	TotalPrice                     int                    `json:"totalPrice"`
+	PriceBreakdown                 PriceBreakdownResponse `json:"priceBreakdown"`
+	EstimatedDeliveryTimeInMinutes int                    `json:"estimatedDeliveryTimeInMinutes"`
+	CalculatedEstimateID           string                 `json:"calculatedEstimateId"`
+	ExpiresAt                      string                 `json:"expiresAt"`
+}
+
+// Reorder a past order to the current location of the user
+type ReorderDTO struct {
+	UserLocation UserLocation `json:"userLocation" binding:"required"`
+	VoucherCode  string       `json:"voucherCode" binding:"max=30"`
+}
+
+// Fresh estimate of the reordered items, warnings tell the items that can not be ordered anymore
+type ReorderResponse struct {
+	OrderEstimationResponse
+	Warnings []string `json:"warnings"`
 }
 
 func (r Request) ValidateRequest() error {
@@ -77,12 +328,228 @@ func (r Request) ValidateRequest() error {
 	return nil
 }
 
+// Stop of the delivery route, user location is the last stop
+type RoutePreviewStop struct {
+	MerchantID string                  `json:"merchantId,omitempty"`
+	Name       string                  `json:"name"`
+	Location   merchantModule.Location `json:"location"`
+	VisitOrder int                     `json:"visitOrder"`
+}
+
+type RoutePreviewLeg struct {
+	FromVisitOrder int     `json:"fromVisitOrder"`
+	ToVisitOrder   int     `json:"toVisitOrder"`
+	DistanceInKm   float64 `json:"distanceInKm"`
+	TimeInMinutes  int     `json:"timeInMinutes"`
+}
+
+type RoutePreviewResponse struct {
+	Stops                          []RoutePreviewStop   `json:"stops"`
+	Legs                           []RoutePreviewLeg    `json:"legs"`
+	TotalDistanceInKm              float64              `json:"totalDistanceInKm"`
+	EstimatedDeliveryTimeInMinutes int                  `json:"estimatedDeliveryTimeInMinutes"`
+	Geometry                       distances.LineString `json:"geometry"`
+}
+
+func FormatRoutePreviewResponse(route distances.Route, eta EtaResult) RoutePreviewResponse {
+	response := RoutePreviewResponse{
+		Stops:                          []RoutePreviewStop{},
+		Legs:                           []RoutePreviewLeg{},
+		TotalDistanceInKm:              route.Distance,
+		EstimatedDeliveryTimeInMinutes: int(math.Round(eta.TotalMinutes)),
+		Geometry:                       route.LineString(),
+	}
+
+	for i, stop := range route.Stops {
+		response.Stops = append(response.Stops, RoutePreviewStop{
+			MerchantID: stop.ID,
+			Name:       stop.Name,
+			Location: merchantModule.Location{
+				Lat:  stop.Lat,
+				Long: stop.Long,
+			},
+			VisitOrder: i + 1,
+		})
+	}
+
+	for i, legDistance := range route.Legs {
+		response.Legs = append(response.Legs, RoutePreviewLeg{
+			FromVisitOrder: i + 1,
+			ToVisitOrder:   i + 2,
+			DistanceInKm:   legDistance,
+			TimeInMinutes:  int(math.Round(eta.LegMinutes[i])),
+		})
+	}
+
+	return response
+}
+
+type EstimationDetailItem struct {
+	ItemID    string            `json:"itemId"`
+	Name      string            `json:"name"`
+	UnitPrice int               `json:"unitPrice"`
+	Quantity  int               `json:"quantity"`
+	Options   []OrderItemOption `json:"options"`
+}
+
+type EstimationDetailMerchant struct {
+	MerchantID       string                  `json:"merchantId"`
+	Name             string                  `json:"name"`
+	Location         merchantModule.Location `json:"location"`
+	IsStartingPoint  bool                    `json:"isStartingPoint"`
+	VisitOrder       int                     `json:"visitOrder"`
+	LegDistanceInKm  float64                 `json:"legDistanceInKm"`
+	LegTimeInMinutes int                     `json:"legTimeInMinutes"`
+	SmallOrderFee    int                     `json:"smallOrderFee"`
+	Items            []EstimationDetailItem  `json:"items"`
+}
+
+type EstimationDetailResponse struct {
+	CalculatedEstimateID           string                     `json:"calculatedEstimateId"`
+	TotalPrice                     int                        `json:"totalPrice"`
+	PriceBreakdown                 PriceBreakdownResponse     `json:"priceBreakdown"`
+	EstimatedDeliveryTimeInMinutes int                        `json:"estimatedDeliveryTimeInMinutes"`
+	UserLocation                   UserLocation               `json:"userLocation"`
+	Merchants                      []EstimationDetailMerchant `json:"merchants"`
+	IsPlaced                       bool                       `json:"isPlaced"`
+	ExpiresAt                      string                     `json:"expiresAt"`
+	CreatedAt                      string                     `json:"createdAt"`
+}
+
+func FormatEstimationDetailResponse(estimation *OrderEstimation, merchants []OrderEstimationMerchant, details []OrderEstimationDetail) EstimationDetailResponse {
+	detailMerchants := []EstimationDetailMerchant{}
+	merchantIndex := make(map[string]int)
+
+	for _, m := range merchants {
+		detailMerchants = append(detailMerchants, EstimationDetailMerchant{
+			MerchantID:       m.MerchantID,
+			IsStartingPoint:  m.IsStartingPoint,
+			VisitOrder:       m.VisitOrder,
+			LegDistanceInKm:  m.LegDistance,
+			LegTimeInMinutes: m.LegTime,
+			SmallOrderFee:    m.SmallOrderFee,
+			Items:            []EstimationDetailItem{},
+		})
+		merchantIndex[m.MerchantID] = len(detailMerchants) - 1
+	}
+
+	// Merchant name and location is taken from the item snapshot
+	for _, d := range details {
+		ix, exists := merchantIndex[d.MerchantID]
+		if !exists {
+			continue
+		}
+
+		detailMerchants[ix].Name = d.MerchantName
+		detailMerchants[ix].Location = merchantModule.Location{
+			Lat:  d.MerchantLocationLat,
+			Long: d.MerchantLocationLong,
+		}
+		detailMerchants[ix].Items = append(detailMerchants[ix].Items, EstimationDetailItem{
+			ItemID:    d.ItemID,
+			Name:      d.ItemName,
+			UnitPrice: d.UnitPrice(),
+			Quantity:  d.Quantity,
+			Options:   d.Options,
+		})
+	}
+
+	return EstimationDetailResponse{
+		CalculatedEstimateID:           estimation.ID,
+		TotalPrice:                     estimation.Price,
+		PriceBreakdown:                 FormatPriceBreakdownResponse(estimation),
+		EstimatedDeliveryTimeInMinutes: estimation.EstimatedTime,
+		UserLocation: UserLocation{
+			Lat:  estimation.UserLat,
+			Long: estimation.UserLong,
+		},
+		Merchants: detailMerchants,
+		IsPlaced:  estimation.ConsumedAt.Valid,
+		ExpiresAt: estimation.ExpiresAt.Format(time.RFC3339),
+		CreatedAt: estimation.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type ReceiptItem struct {
+	ItemID    string            `json:"itemId"`
+	Name      string            `json:"name"`
+	UnitPrice int               `json:"unitPrice"`
+	Quantity  int               `json:"quantity"`
+	Options   []OrderItemOption `json:"options"`
+	Subtotal  int               `json:"subtotal"`
+}
+
+type ReceiptMerchant struct {
+	MerchantID string                  `json:"merchantId"`
+	Name       string                  `json:"name"`
+	Location   merchantModule.Location `json:"location"`
+	Items      []ReceiptItem           `json:"items"`
+	Subtotal   int                     `json:"subtotal"`
+}
+
+// Receipt of placed order.
+// It is rendered from the snapshot stored on the estimation.
+type OrderReceiptResponse struct {
+	OrderId                        string                 `json:"orderId"`
+	Status                         OrderStatus            `json:"status"`
+	Merchants                      []ReceiptMerchant      `json:"merchants"`
+	TotalPrice                     int                    `json:"totalPrice"`
+	PriceBreakdown                 PriceBreakdownResponse `json:"priceBreakdown"`
+	EstimatedDeliveryTimeInMinutes int                    `json:"estimatedDeliveryTimeInMinutes"`
+	CreatedAt                      string                 `json:"createdAt"`
+}
+
+func FormatOrderReceiptResponse(order *PlacedOrder, estimation *OrderEstimation, details []OrderEstimationDetail) OrderReceiptResponse {
+	merchants := []ReceiptMerchant{}
+	merchantIndex := make(map[string]int)
+
+	for _, d := range details {
+		ix, exists := merchantIndex[d.MerchantID]
+		if !exists {
+			merchants = append(merchants, ReceiptMerchant{
+				MerchantID: d.MerchantID,
+				Name:       d.MerchantName,
+				Location: merchantModule.Location{
+					Lat:  d.MerchantLocationLat,
+					Long: d.MerchantLocationLong,
+				},
+				Items: []ReceiptItem{},
+			})
+			ix = len(merchants) - 1
+			merchantIndex[d.MerchantID] = ix
+		}
+
+		subtotal := d.UnitPrice() * d.Quantity
+
+		merchants[ix].Items = append(merchants[ix].Items, ReceiptItem{
+			ItemID:    d.ItemID,
+			Name:      d.ItemName,
+			UnitPrice: d.UnitPrice(),
+			Quantity:  d.Quantity,
+			Options:   d.Options,
+			Subtotal:  subtotal,
+		})
+		merchants[ix].Subtotal += subtotal
+	}
+
+	return OrderReceiptResponse{
+		OrderId:                        order.ID,
+		Status:                         order.Status,
+		Merchants:                      merchants,
+		TotalPrice:                     estimation.Price,
+		PriceBreakdown:                 FormatPriceBreakdownResponse(estimation),
+		EstimatedDeliveryTimeInMinutes: estimation.EstimatedTime,
+		CreatedAt:                      order.CreatedAt.Format(time.RFC3339),
+	}
+}
+
 type GetOrderHistQueryParams struct {
 	MerchantID       string             `form:"merchantId"`
 	Limit            int                `form:"limit"`
 	Offset           int                `form:"offset"`
 	Name             string             `form:"name"`
 	MerchantCategory merchantModule.MerchantCategories `form:"merchantCategory"`
+	Cursor           string             `form:"cursor"` // Replace offset with the nextCursor of the previous page
 }
 
 type GetOrderHistQueryResult struct {
@@ -101,6 +568,9 @@ type GetOrderHistQueryResult struct {
 	ItemImageUrl      string             `json:"itemImageUrl" db:"item_image_url"`
 	ItemCreatedAt     time.Time          `json:"itemCreatedAt" db:"item_created_at"`
 	Quantity          int            	 `json:"quantity" db:"quantity"`
+	Options           OrderItemOptions   `json:"options" db:"options"`
+	Status            OrderStatus        `json:"status" db:"status"`
+	OrderCreatedAt    time.Time          `json:"-" db:"order_created_at"`
 }
 
 type OrderHistMerchant struct {
@@ -116,14 +586,16 @@ type OrderHistItem struct {
 	ID              string            `json:"itemId"`
 	Name            string            `json:"name"`
 	ProductCategory merchantModule.ProductCategories `json:"productCategory"`
-	Price           int               `json:"price"`
+	Price           int               `json:"price"` // Unit price including the selected options
 	Quantity        int            	  `json:"quantity"`
+	Options         []OrderItemOption `json:"options"`
 	ImageUrl        string            `json:"imageUrl"`
 	CreatedAt       time.Time         `json:"createdAt"`
 }
 
 type GetOrderHistResponse struct {
 	OrderId	 string				`json:"orderId"`
+	Status   OrderStatus        `json:"status"`
 	Merchant OrderHistMerchant  `json:"merchant"`
 	Items    []OrderHistItem	`json:"items"`
 }
@@ -135,9 +607,15 @@ type GetOrderHistResponseOnly struct {
 
 type GetOrderHistResponseWithOrderId struct {
 	OrderId	 	string						`json:"orderId"`
+	Status      OrderStatus                 `json:"status"`
 	Orders		[]GetOrderHistResponseOnly	`json:"orders"`
 }
 
+type GetOrderHistResponseAndMeta struct {
+	Data []GetOrderHistResponseWithOrderId `json:"data"`
+	Meta merchantModule.Meta               `json:"meta"`
+}
+
 func FormatGetOrderHistResponseWithOrderId(data []GetOrderHistResponse) []GetOrderHistResponseWithOrderId {
 	ordersWithId := []GetOrderHistResponseWithOrderId{}
 	orderWithId := GetOrderHistResponseWithOrderId{}
@@ -155,6 +633,7 @@ func FormatGetOrderHistResponseWithOrderId(data []GetOrderHistResponse) []GetOrd
 		if ix+1 == totalLen {
 			orderWithId = GetOrderHistResponseWithOrderId{
 				OrderId: o.OrderId,
+				Status: o.Status,
 				Orders: orders,
 			}
 			ordersWithId = append(ordersWithId, orderWithId)
@@ -162,6 +641,7 @@ func FormatGetOrderHistResponseWithOrderId(data []GetOrderHistResponse) []GetOrd
 			if o.OrderId != data[ix+1].OrderId {
 				orderWithId = GetOrderHistResponseWithOrderId{
 					OrderId: o.OrderId,
+					Status: o.Status,
 					Orders: orders,
 				}
 				ordersWithId = append(ordersWithId, orderWithId)
@@ -203,12 +683,14 @@ func FormatGetOrderHistResponse(orders []GetOrderHistQueryResult) []GetOrderHist
 			ImageUrl:        m.ItemImageUrl,
 			CreatedAt:       m.ItemCreatedAt,
 			Quantity:		 m.Quantity,
+			Options:         m.Options,
 		}
 		items = append(items, item)
 
 		if ix+1 == totalLen {
 			merchantAndItems = GetOrderHistResponse{
 				OrderId: m.OrderId,
+				Status: m.Status,
 				Merchant: merchant,
 				Items:    items,
 			}
@@ -217,6 +699,7 @@ func FormatGetOrderHistResponse(orders []GetOrderHistQueryResult) []GetOrderHist
 			if m.OrderId+m.MerchantId != orders[ix+1].OrderId+orders[ix+1].MerchantId {
 				merchantAndItems = GetOrderHistResponse{
 					OrderId: m.OrderId,
+					Status: m.Status,
 					Merchant: merchant,
 					Items:    items,
 				}
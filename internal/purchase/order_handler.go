@@ -2,10 +2,17 @@ package purchase
 
 import (
 	"belimang/internal/middleware"
+	"belimang/internal/user"
 	"belimang/pkg/response"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"strings"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 type orderHandler struct {
@@ -26,9 +33,35 @@ func (h *orderHandler) Router(r *gin.RouterGroup) {
 	)
 
 	// Routing
-	group.POST("estimate", h.Estimate)
-	group.POST("orders", h.Order)
+	group.POST("estimate", h.UseIdempotencyKey, h.Estimate)
+	group.GET("estimate/:id", h.EstimationDetail)
+	group.POST("route-preview", h.RoutePreview)
+	group.POST("orders", h.UseIdempotencyKey, h.Order)
 	group.GET("orders", h.OrderHistory)
+	group.POST("orders/:orderId/cancel", h.ValidateOrderID, h.CancelOrder)
+	group.GET("orders/:orderId/receipt", h.ValidateOrderID, h.Receipt)
+	group.POST("orders/:orderId/reorder", h.ValidateOrderID, h.UseIdempotencyKey, h.Reorder)
+
+	// Order lifecycle is handled by admin
+	adminGroup := r.Group(
+		"admin/orders",
+		middleware.UseJwtAuth,
+		middleware.HasRoles(string(user.ADMIN)),
+	)
+
+	adminGroup.PATCH("/:orderId/status", h.ValidateOrderID, h.UpdateOrderStatus)
+	adminGroup.POST("/:orderId/reject", h.ValidateOrderID, h.RejectOrder)
+	adminGroup.POST("/:orderId/refund", h.ValidateOrderID, h.ProcessRefund)
+
+	// Merchant side of the order lifecycle, merchant is managed by admin
+	merchantGroup := r.Group(
+		"admin/merchants/:merchantId/orders",
+		middleware.UseJwtAuth,
+		middleware.HasRoles(string(user.ADMIN)),
+		middleware.ValidateUUIDParam("merchantId", "Merchant data not found"),
+	)
+
+	merchantGroup.PATCH("/:orderId/status", h.ValidateOrderID, h.UpdateMerchantOrderStatus)
 }
 
 func (h *orderHandler) Estimate(c *gin.Context) {
@@ -68,9 +101,64 @@ func (h *orderHandler) Estimate(c *gin.Context) {
 	response.GenerateResponseReturnData(c, 200, response.WithData(result))
 }
 
+// RoutePreview show the delivery route of the order before it is estimated
+func (h *orderHandler) RoutePreview(c *gin.Context) {
+	var req Request
+
+	// Parse request body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if strings.Contains(err.Error(), "failed on the 'uuid' tag") {
+			response.GenerateResponse(c, http.StatusNotFound, response.WithMessage(err.Error()))
+		} else {
+			response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		}
+		c.Abort()
+		return
+	}
+
+	// Validate the starting point
+	if err := req.ValidateRequest(); err != nil {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.RoutePreview(req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) EstimationDetail(c *gin.Context) {
+	userId := c.GetString("userID")
+	estimationId := c.Param("id")
+
+	// Estimation ID that is not UUID will never exists
+	if _, err := uuid.Parse(estimationId); err != nil {
+		response.GenerateResponse(c, http.StatusNotFound, response.WithMessage("Estimation data not found"))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.EstimationDetail(userId, estimationId)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
 func (h *orderHandler) Order(c *gin.Context) {
 	var entity ActualOrder
 
+	userId := c.GetString("userID")
+
 	// Parse request body to struct
 	if err := c.ShouldBindJSON(&entity); err != nil {
 		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
@@ -78,7 +166,14 @@ func (h *orderHandler) Order(c *gin.Context) {
 		return
 	}
 
-	result, err := h.usecase.PlaceOrder(entity)
+	// Estimation ID that is not UUID will never exists
+	if _, err := uuid.Parse(entity.OrderEstimationId); err != nil {
+		response.GenerateResponse(c, http.StatusNotFound, response.WithMessage("Estimation data not found"))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.PlaceOrder(userId, entity)
 	if err != nil {
 		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
 		c.Abort()
@@ -108,4 +203,242 @@ func (h *orderHandler) OrderHistory(c *gin.Context) {
 	}
 
 	response.GenerateResponseReturnData(c, 200, response.WithData(result))
-}
\ No newline at end of file
+}
+
+func (h *orderHandler) UpdateOrderStatus(c *gin.Context) {
+	var req UpdateOrderStatusDTO
+
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	// Parse request body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.UpdateOrderStatus(orderId, userId, req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) UpdateMerchantOrderStatus(c *gin.Context) {
+	var req UpdateMerchantOrderStatusDTO
+
+	userId := c.GetString("userID")
+	merchantId := c.Param("merchantId")
+	orderId := c.Param("orderId")
+
+	// Parse request body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.UpdateMerchantOrderStatus(merchantId, orderId, userId, req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) CancelOrder(c *gin.Context) {
+	var req CancelOrderDTO
+
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	// Reason is optional, so empty body is allowed
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+			c.Abort()
+			return
+		}
+	}
+
+	result, err := h.usecase.CancelOrder(userId, orderId, req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+// Reorder estimate the items of a past order again from the current location of the user
+func (h *orderHandler) Reorder(c *gin.Context) {
+	var req ReorderDTO
+
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		c.Abort()
+		return
+	}
+
+	result, err := h.usecase.Reorder(userId, orderId, req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) RejectOrder(c *gin.Context) {
+	var req CancelOrderDTO
+
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	// Reason is optional, so empty body is allowed
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+			c.Abort()
+			return
+		}
+	}
+
+	result, err := h.usecase.RejectOrder(orderId, userId, req)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) ProcessRefund(c *gin.Context) {
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	result, err := h.usecase.ProcessRefund(orderId, userId)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+func (h *orderHandler) Receipt(c *gin.Context) {
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	result, err := h.usecase.Receipt(userId, orderId)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, 200, response.WithData(result))
+}
+
+// Make sure order ID in the URL is a valid UUID before it is sent to database
+func (h *orderHandler) ValidateOrderID(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("orderId")); err != nil {
+		response.GenerateResponse(c, http.StatusNotFound, response.WithMessage("Order data not found"))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// Make the request safe to retry using Idempotency-Key header.
+// Retried request with the same payload get the original response,
+// while a different payload with the same key is rejected.
+func (h *orderHandler) UseIdempotencyKey(c *gin.Context) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+
+	// Header is optional
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	if len(key) > 255 {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage("Idempotency key is too long"))
+		c.Abort()
+		return
+	}
+
+	// Read the body to get the request hash, then put it back for the next handler
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.GenerateResponse(c, http.StatusBadRequest, response.WithMessage(err.Error()))
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	hash := sha256.Sum256(body)
+
+	// Key is scoped to the requested path, so the same key used for another order is not replayed
+	entity := IdempotencyKey{
+		UserID:      c.GetString("userID"),
+		Endpoint:    c.Request.Method + " " + c.Request.URL.Path,
+		Key:         key,
+		RequestHash: hex.EncodeToString(hash[:]),
+	}
+
+	stored, gErr := h.usecase.ReserveIdempotencyKey(entity)
+	if gErr != nil {
+		response.GenerateResponse(c, gErr.Code, response.WithMessage(gErr.Message))
+		c.Abort()
+		return
+	}
+
+	// Replay the original response
+	if stored != nil {
+		c.Data(int(stored.ResponseCode.Int32), "application/json; charset=utf-8", stored.ResponseBody)
+		c.Abort()
+		return
+	}
+
+	writer := idempotencyResponseWriter{
+		ResponseWriter: c.Writer,
+		body:           &bytes.Buffer{},
+	}
+	c.Writer = writer
+
+	// Key is released unless the response is stored, including when the next handler panics,
+	// so the client can retry the request instead of waiting for the key to expire
+	saved := false
+	defer func() {
+		if !saved {
+			h.usecase.ReleaseIdempotencyKey(entity)
+		}
+	}()
+
+	c.Next()
+
+	// Server error and request aborted without response are not stored, so the client can retry the request
+	if !writer.Written() || writer.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	entity.ResponseCode = sql.NullInt32{Int32: int32(writer.Status()), Valid: true}
+	entity.ResponseBody = writer.body.Bytes()
+
+	saved = h.usecase.SaveIdempotencyResponse(entity) == nil
+}
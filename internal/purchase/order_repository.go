@@ -1,156 +1,767 @@
 package purchase
 
 import (
+	"belimang/internal/promotion"
 	localError "belimang/pkg/error"
+	"belimang/pkg/sqlbuilder"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type IOrderRepository interface {
-	CreateEstimation(entity *OrderEstimation) (string, *localError.GlobalError)
-	CreateOrderMerchant(orderEstimationID string, entity []OrderEstimationDetail) *localError.GlobalError
-	PlaceOrder(orderEstimationID string) (string, *localError.GlobalError)
-	OrderHistory(userId string, params GetOrderHistQueryParams) ([]GetOrderHistQueryResult, *localError.GlobalError)
+	CreateEstimation(entity *OrderEstimation, items []OrderEstimationDetail, merchants []OrderEstimationMerchant) (string, *localError.GlobalError)
+	PlaceOrder(userId string, orderEstimationID string) (string, *localError.GlobalError)
+	OrderHistory(userId string, params GetOrderHistQueryParams) ([]GetOrderHistQueryResult, int, *localError.GlobalError)
+	FindOrderById(orderId string) (*PlacedOrder, *localError.GlobalError)
+	UpdateOrderStatus(order *PlacedOrder, status OrderStatus, changedBy string, note string) *localError.GlobalError
+	CancelOrder(order *PlacedOrder, changedBy string, reason string) (*Refund, *localError.GlobalError)
+	ProcessRefund(orderId string, processedBy string) (*Refund, *localError.GlobalError)
+	FindEstimationById(orderEstimationID string) (*OrderEstimation, *localError.GlobalError)
+	FindEstimationItems(orderEstimationID string) ([]OrderEstimationDetail, *localError.GlobalError)
+	FindEstimationMerchants(orderEstimationID string) ([]OrderEstimationMerchant, *localError.GlobalError)
+	ReleaseExpiredStock() (int64, *localError.GlobalError)
 }
 
 type orderRepository struct {
 	db *sqlx.DB
 }
 
-// PlaceOrder implements IOrderRepository.
-func (repo *orderRepository) PlaceOrder(orderEstimationID string) (string, *localError.GlobalError) {
+// PlaceOrder consume the user estimation and create the order.
+// Estimation is locked during the process, so the same estimation can only be placed once.
+func (repo *orderRepository) PlaceOrder(userId string, orderEstimationID string) (string, *localError.GlobalError) {
+	// Order ID
+	var id string
+
+	// Estimation, order and its first status history is stored together
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
 	// Search for estimation ID
-	searchQuery := "SELECT id from order_estimation where id = $1"
+	estimation := OrderEstimation{}
+	searchQuery := "SELECT * FROM order_estimation WHERE id = $1 FOR UPDATE"
+
+	err = tx.Get(&estimation, searchQuery, orderEstimationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", localError.ErrNotFound("Estimation data not found", err)
+		}
 
-	estErr := repo.db.QueryRow(searchQuery, orderEstimationID).Err()
-	if estErr != nil {
-		return "", localError.ErrNotFound("Estimation data not found", estErr)
+		return "", localError.ErrInternalServer(err.Error(), err)
 	}
 
-	// Order ID
-	var id string
+	// Estimation of another user is treated as not found
+	if estimation.UserID != userId {
+		return "", localError.ErrNotFound("Estimation data not found", fmt.Errorf("estimation is not owned by user"))
+	}
+
+	if estimation.ConsumedAt.Valid {
+		return "", localError.ErrConflict("Estimation has already been used to place an order", fmt.Errorf("estimation is consumed"))
+	}
+
+	if time.Now().After(estimation.ExpiresAt) {
+		return "", localError.ErrGone("Estimation has expired, please estimate the order again", fmt.Errorf("estimation is expired"))
+	}
+
+	// Voucher may have ended or been used up since the estimation is created
+	if estimation.PromotionID.Valid {
+		if err := checkPromotionTx(tx, estimation.PromotionID.String, userId); err != nil {
+			return "", err
+		}
+	}
+
+	// Reserved stock of the estimation now belongs to the order
+	if err := commitStockTx(tx, orderEstimationID); err != nil {
+		return "", err
+	}
+
+	consumeQ := "UPDATE order_estimation SET consumed_at = CURRENT_TIMESTAMP WHERE id = $1"
+
+	_, err = tx.Exec(consumeQ, orderEstimationID)
+	if err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
 
 	// Construct query
-	q := "INSERT INTO orders (order_estimation_id) values ($1) returning id"
+	q := "INSERT INTO orders (order_estimation_id, status) values ($1, $2) returning id"
+
+	err = tx.QueryRowx(q, orderEstimationID, OrderPlaced).Scan(&id)
+	if err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
 
-	err := repo.db.QueryRowx(q, orderEstimationID).Scan(&id)
+	historyQ := "INSERT INTO order_status_histories (order_id, to_status) values ($1, $2)"
+
+	_, err = tx.Exec(historyQ, id, OrderPlaced)
 	if err != nil {
 		return "", localError.ErrInternalServer(err.Error(), err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
+
 	return id, nil
 }
 
-// CreateOrderMerchant implements IOrderRepository.
-func (repo *orderRepository) CreateOrderMerchant(orderEstimationID string, entity []OrderEstimationDetail) *localError.GlobalError {
-	log.Println(orderEstimationID)
-	// Construct insert query & param
-	q := "INSERT INTO order_estimation_items (order_estimation_id,item_id,quantity) VALUES "
-	var insertParam []any
+// FindOrderById get placed order with the owner of the order
+func (repo *orderRepository) FindOrderById(orderId string) (*PlacedOrder, *localError.GlobalError) {
+	order := PlacedOrder{}
 
-	// Loop to get the full data to be stored
-	for i, data := range entity {
-		pos := i * 3
+	q := `
+		SELECT o.id, o.order_estimation_id, oe.user_id, o.status, o.created_at, o.updated_at
+		FROM orders o
+		INNER JOIN order_estimation oe ON o.order_estimation_id = oe.id
+		WHERE o.id = $1
+	`
 
-		// Generate placeholder
-		q += fmt.Sprintf("($%d,$%d,$%d),", pos+1, pos+2, pos+3)
+	if err := repo.db.Get(&order, q, orderId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, localError.ErrNotFound("Order data not found", err)
+		}
 
-		// Generate binding value
-		insertParam = append(insertParam, orderEstimationID, data.ItemID, data.Quantity)
+		return nil, localError.ErrInternalServer(err.Error(), err)
 	}
 
-	q = q[:len(q)-1] // Hilangkan ","
+	return &order, nil
+}
 
-	log.Println(q, insertParam)
-	_, err := repo.db.Exec(q, insertParam...)
+// UpdateOrderStatus move the order to the new status and record it to the status history.
+// The update only applied if the order status is not changed by another request.
+func (repo *orderRepository) UpdateOrderStatus(order *PlacedOrder, status OrderStatus, changedBy string, note string) *localError.GlobalError {
+	tx, err := repo.db.Beginx()
 	if err != nil {
 		return localError.ErrInternalServer(err.Error(), err)
 	}
+	defer tx.Rollback()
+
+	if err := updateOrderStatusTx(tx, order, status, changedBy, note); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// CancelOrder move the order to cancelled status and create the refund
+// using total price of the order estimation in a single transaction.
+func (repo *orderRepository) CancelOrder(order *PlacedOrder, changedBy string, reason string) (*Refund, *localError.GlobalError) {
+	refund := Refund{}
+
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
+	if err := updateOrderStatusTx(tx, order, OrderCancelled, changedBy, reason); err != nil {
+		return nil, err
+	}
+
+	// Give back the stock of cancelled order
+	if err := releaseStockTx(tx, order.OrderEstimationID, StockCommitted); err != nil {
+		return nil, err
+	}
+
+	q := `
+		INSERT INTO refunds (order_id, amount, reason, requested_by)
+		SELECT o.id, oe.total_price, NULLIF($2, ''), $3
+		FROM orders o
+		INNER JOIN order_estimation oe ON o.order_estimation_id = oe.id
+		WHERE o.id = $1
+		RETURNING *
+	`
+
+	err = tx.QueryRowx(q, order.ID, reason, changedBy).StructScan(&refund)
+	if err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &refund, nil
+}
+
+// ProcessRefund mark pending refund of the order as processed
+func (repo *orderRepository) ProcessRefund(orderId string, processedBy string) (*Refund, *localError.GlobalError) {
+	refund := Refund{}
+
+	q := `
+		UPDATE refunds
+		SET status = $1, processed_by = $2, processed_at = CURRENT_TIMESTAMP
+		WHERE order_id = $3 AND status = $4
+		RETURNING *
+	`
+
+	err := repo.db.QueryRowx(q, RefundProcessed, processedBy, orderId, RefundPending).StructScan(&refund)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, localError.ErrNotFound("Pending refund not found", err)
+		}
+
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &refund, nil
+}
+
+// Update order status and store the status history inside the given transaction
+func updateOrderStatusTx(tx *sqlx.Tx, order *PlacedOrder, status OrderStatus, changedBy string, note string) *localError.GlobalError {
+	q := "UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND status = $3 RETURNING updated_at"
+
+	err := tx.QueryRowx(q, status, order.ID, order.Status).Scan(&order.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return localError.ErrConflict("Order status has been changed, please try again", err)
+		}
+
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	historyQ := `
+		INSERT INTO order_status_histories (order_id, from_status, to_status, changed_by, note)
+		values ($1, $2, $3, $4, NULLIF($5, ''))
+	`
+
+	_, err = tx.Exec(historyQ, order.ID, order.Status, status, changedBy, note)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	order.Status = status
 
 	return nil
 }
 
 // CreateEstimation store the user estimated order time and price
-func (repo *orderRepository) CreateEstimation(entity *OrderEstimation) (string, *localError.GlobalError) {
+// together with the ordered items and the visited merchants in a single transaction.
+func (repo *orderRepository) CreateEstimation(entity *OrderEstimation, items []OrderEstimationDetail, merchants []OrderEstimationMerchant) (string, *localError.GlobalError) {
 	var id string
 
+	tx, err := repo.db.Beginx()
+	if err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
 	// Insert Query
 	q := `INSERT INTO order_estimation 
-			(user_id,user_location_lat,user_location_long,total_price,estimated_delivery_time) 
+			(user_id,user_location_lat,user_location_long,total_price,estimated_delivery_time,expires_at,items_price,delivery_fee,small_order_fee,service_fee,tax,promotion_id,voucher_code,discount) 
 			values 
-				($1,$2,$3,$4,$5)
+				($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
 			RETURNING id
 		`
 
-	err := repo.db.QueryRowx(
+	err = tx.QueryRowx(
 		q,
 		entity.UserID,
 		entity.UserLat,
 		entity.UserLong,
 		entity.Price,
 		entity.EstimatedTime,
+		entity.ExpiresAt,
+		entity.ItemsPrice,
+		entity.DeliveryFee,
+		entity.SmallOrderFee,
+		entity.ServiceFee,
+		entity.Tax,
+		entity.PromotionID,
+		entity.VoucherCode,
+		entity.Discount,
 	).Scan(&id)
 
 	if err != nil {
 		return "", localError.ErrInternalServer(err.Error(), err)
 	}
 
+	if err := createEstimationItemsTx(tx, id, items); err != nil {
+		return "", err
+	}
+
+	if err := createEstimationMerchantsTx(tx, id, merchants); err != nil {
+		return "", err
+	}
+
+	// Only the latest estimation of the user holds stock of the same item
+	if err := releaseUserStockTx(tx, entity.UserID, items); err != nil {
+		return "", err
+	}
+
+	if err := reserveStockTx(tx, id, items); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", localError.ErrInternalServer(err.Error(), err)
+	}
+
 	return id, nil
 }
 
-func (repo *orderRepository) OrderHistory(userId string, params GetOrderHistQueryParams) ([]GetOrderHistQueryResult, *localError.GlobalError) {
+// Check the promotion can still be used by the user.
+// Promotion is locked until the order is stored, so concurrent orders can't exceed its usage limit.
+func checkPromotionTx(tx *sqlx.Tx, promotionId string, userId string) *localError.GlobalError {
+	p := promotion.Promotion{}
+
+	if err := tx.Get(&p, "SELECT * FROM promotions WHERE id = $1 FOR UPDATE", promotionId); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if !p.IsActiveAt(time.Now()) {
+		return localError.ErrConflict("Voucher is no longer active, please estimate the order again", fmt.Errorf("promotion %s is not active", promotionId))
+	}
+
+	usage, err := promotion.CountUsage(tx, promotionId, userId)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if !p.IsUsable(usage) {
+		return localError.ErrConflict("Voucher usage limit has been reached, please estimate the order again", fmt.Errorf("promotion %s is used up", promotionId))
+	}
+
+	return nil
+}
+
+// Store ordered items of the estimation
+func createEstimationItemsTx(tx *sqlx.Tx, orderEstimationID string, entity []OrderEstimationDetail) *localError.GlobalError {
+	// Construct insert query & param
+	q := `INSERT INTO order_estimation_items 
+		(order_estimation_id,item_id,quantity,merchant_id,merchant_name,merchant_location_lat,merchant_location_long,item_name,item_price,options,options_price,
+		merchant_category,merchant_image_url,merchant_created_at,item_product_category,item_image_url,item_created_at) 
+		VALUES `
+	var insertParam []any
+
+	// Loop to get the full data to be stored
+	for i, data := range entity {
+		pos := i * 17
+
+		// Generate placeholder
+		q += fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d),", pos+1, pos+2, pos+3, pos+4, pos+5, pos+6, pos+7, pos+8, pos+9, pos+10, pos+11, pos+12, pos+13, pos+14, pos+15, pos+16, pos+17)
+
+		// Generate binding value
+		insertParam = append(
+			insertParam,
+			orderEstimationID,
+			data.ItemID,
+			data.Quantity,
+			data.MerchantID,
+			data.MerchantName,
+			data.MerchantLocationLat,
+			data.MerchantLocationLong,
+			data.ItemName,
+			data.ItemPrice,
+			data.Options,
+			data.OptionsPrice,
+			data.MerchantCategory,
+			data.MerchantImageUrl,
+			data.MerchantCreatedAt,
+			data.ProductCategory,
+			data.ItemImageUrl,
+			data.ItemCreatedAt,
+		)
+	}
+
+	q = q[:len(q)-1] // Hilangkan ","
+
+	_, err := tx.Exec(q, insertParam...)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Store visited merchants of the estimation with its route leg
+func createEstimationMerchantsTx(tx *sqlx.Tx, orderEstimationID string, entity []OrderEstimationMerchant) *localError.GlobalError {
+	// Construct insert query & param
+	q := `INSERT INTO order_estimation_merchants 
+		(order_estimation_id,merchant_id,is_starting_point,visit_order,leg_distance,leg_time,small_order_fee) 
+		VALUES `
+	var insertParam []any
+
+	for i, data := range entity {
+		pos := i * 7
+
+		// Generate placeholder
+		q += fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d),", pos+1, pos+2, pos+3, pos+4, pos+5, pos+6, pos+7)
+
+		// Generate binding value
+		insertParam = append(
+			insertParam,
+			orderEstimationID,
+			data.MerchantID,
+			data.IsStartingPoint,
+			data.VisitOrder,
+			data.LegDistance,
+			data.LegTime,
+			data.SmallOrderFee,
+		)
+	}
+
+	q = q[:len(q)-1] // Hilangkan ","
+
+	_, err := tx.Exec(q, insertParam...)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Reserve stock of the ordered items that track its stock.
+// Items are updated in the same order, so concurrent estimations won't deadlock,
+// and the stock check is done in the update itself so it can't be oversold.
+func reserveStockTx(tx *sqlx.Tx, orderEstimationID string, items []OrderEstimationDetail) *localError.GlobalError {
+	var itemIDs []string
+	quantities := make(map[string]int)
+	names := make(map[string]string)
+
+	// Same item can be ordered more than once with different options
+	for _, item := range items {
+		if _, exists := quantities[item.ItemID]; !exists {
+			itemIDs = append(itemIDs, item.ItemID)
+		}
+
+		quantities[item.ItemID] += item.Quantity
+		names[item.ItemID] = item.ItemName
+	}
+
+	sort.Strings(itemIDs)
+
+	for _, itemID := range itemIDs {
+		var tracked bool
+
+		q := "UPDATE items SET stock = stock - $2 WHERE id = $1 AND (stock IS NULL OR stock >= $2) RETURNING stock IS NOT NULL"
+
+		err := tx.QueryRowx(q, itemID, quantities[itemID]).Scan(&tracked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				message := fmt.Sprintf("Insufficient stock: %s", names[itemID])
+				return localError.ErrConflict(message, &InsufficientStockError{ItemID: itemID, ItemName: names[itemID], Quantity: quantities[itemID]})
+			}
+
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+
+		if !tracked {
+			continue
+		}
+
+		reserveQ := "INSERT INTO stock_reservations (order_estimation_id, item_id, quantity) values ($1, $2, $3)"
+
+		if _, err := tx.Exec(reserveQ, orderEstimationID, itemID, quantities[itemID]); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	return nil
+}
+
+// Release stock reserved by the user's estimations that are not placed yet for the given items.
+// Estimation being placed is locked, so its stock is either committed first or released here.
+func releaseUserStockTx(tx *sqlx.Tx, userID string, items []OrderEstimationDetail) *localError.GlobalError {
+	itemIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	q := `
+		WITH previous AS (
+			SELECT sr.order_estimation_id, sr.item_id
+			FROM stock_reservations sr
+			INNER JOIN order_estimation oe ON sr.order_estimation_id = oe.id
+			WHERE oe.user_id = ? AND oe.consumed_at IS NULL AND sr.status = ? AND sr.item_id IN (?)
+			FOR UPDATE OF sr, oe
+		),
+		released AS (
+			UPDATE stock_reservations sr
+			SET status = ?, updated_at = CURRENT_TIMESTAMP
+			FROM previous p
+			WHERE sr.order_estimation_id = p.order_estimation_id AND sr.item_id = p.item_id
+			RETURNING sr.item_id, sr.quantity
+		)
+		UPDATE items i
+		SET stock = i.stock + r.quantity
+		FROM (SELECT item_id, SUM(quantity) AS quantity FROM released GROUP BY item_id) r
+		WHERE i.id = r.item_id AND i.stock IS NOT NULL
+	`
+
+	query, args, err := sqlx.In(q, userID, StockReserved, itemIDs, StockReleased)
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Commit reserved stock of the estimation when the order is placed.
+// Estimation whose stock has been released is treated as expired.
+func commitStockTx(tx *sqlx.Tx, orderEstimationID string) *localError.GlobalError {
+	var released int
+
+	countQ := "SELECT COUNT(*) FROM stock_reservations WHERE order_estimation_id = $1 AND status = $2"
+
+	if err := tx.Get(&released, countQ, orderEstimationID, StockReleased); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if released > 0 {
+		return localError.ErrGone("Estimation has expired, please estimate the order again", fmt.Errorf("estimation stock is released"))
+	}
+
+	q := "UPDATE stock_reservations SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE order_estimation_id = $2 AND status = $3"
+
+	if _, err := tx.Exec(q, StockCommitted, orderEstimationID, StockReserved); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// Release stock of the estimation that has the given reservation status
+// and give it back to the item stock
+func releaseStockTx(tx *sqlx.Tx, orderEstimationID string, status StockReservationStatus) *localError.GlobalError {
+	q := `
+		WITH released AS (
+			UPDATE stock_reservations
+			SET status = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE order_estimation_id = $2 AND status = $3
+			RETURNING item_id, quantity
+		)
+		UPDATE items i
+		SET stock = i.stock + r.quantity
+		FROM released r
+		WHERE i.id = r.item_id AND i.stock IS NOT NULL
+	`
+
+	if _, err := tx.Exec(q, StockReleased, orderEstimationID, status); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// ReleaseExpiredStock give back stock reserved by estimations that expire without being placed.
+// Estimation that is being placed is locked, so it is skipped and checked on the next run.
+func (repo *orderRepository) ReleaseExpiredStock() (int64, *localError.GlobalError) {
+	var released int64
+
+	q := `
+		WITH expired AS (
+			SELECT sr.order_estimation_id, sr.item_id
+			FROM stock_reservations sr
+			INNER JOIN order_estimation oe ON sr.order_estimation_id = oe.id
+			WHERE sr.status = $1 AND oe.consumed_at IS NULL AND oe.expires_at < CURRENT_TIMESTAMP
+			FOR UPDATE OF sr, oe SKIP LOCKED
+		),
+		released AS (
+			UPDATE stock_reservations sr
+			SET status = $2, updated_at = CURRENT_TIMESTAMP
+			FROM expired e
+			WHERE sr.order_estimation_id = e.order_estimation_id AND sr.item_id = e.item_id
+			RETURNING sr.item_id, sr.quantity
+		),
+		restocked AS (
+			UPDATE items i
+			SET stock = i.stock + r.quantity
+			FROM (SELECT item_id, SUM(quantity) AS quantity FROM released GROUP BY item_id) r
+			WHERE i.id = r.item_id AND i.stock IS NOT NULL
+		)
+		SELECT COUNT(*) FROM released
+	`
+
+	if err := repo.db.Get(&released, q, StockReserved, StockReleased); err != nil {
+		return 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return released, nil
+}
+
+// FindEstimationMerchants get visited merchants of an estimation ordered by the visiting order
+func (repo *orderRepository) FindEstimationMerchants(orderEstimationID string) ([]OrderEstimationMerchant, *localError.GlobalError) {
+	merchants := []OrderEstimationMerchant{}
+
+	q := `
+		SELECT order_estimation_id, merchant_id, is_starting_point, visit_order, leg_distance, leg_time, small_order_fee
+		FROM order_estimation_merchants
+		WHERE order_estimation_id = $1
+		ORDER BY visit_order
+	`
+
+	if err := repo.db.Select(&merchants, q, orderEstimationID); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return merchants, nil
+}
+
+// FindEstimationById get single order estimation
+func (repo *orderRepository) FindEstimationById(orderEstimationID string) (*OrderEstimation, *localError.GlobalError) {
+	estimation := OrderEstimation{}
+
+	if err := repo.db.Get(&estimation, "SELECT * FROM order_estimation WHERE id = $1", orderEstimationID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, localError.ErrNotFound("Estimation data not found", err)
+		}
+
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return &estimation, nil
+}
+
+// FindEstimationItems get the snapshot of ordered items of an estimation
+func (repo *orderRepository) FindEstimationItems(orderEstimationID string) ([]OrderEstimationDetail, *localError.GlobalError) {
+	details := []OrderEstimationDetail{}
+
+	q := `
+		SELECT
+			order_estimation_id,
+			item_id,
+			quantity,
+			merchant_id,
+			merchant_name,
+			merchant_location_lat,
+			merchant_location_long,
+			item_name,
+			item_price,
+			options,
+			options_price,
+			merchant_category,
+			merchant_image_url,
+			merchant_created_at,
+			item_product_category,
+			item_image_url,
+			item_created_at
+		FROM order_estimation_items
+		WHERE order_estimation_id = $1
+		ORDER BY merchant_id, item_name
+	`
+
+	if err := repo.db.Select(&details, q, orderEstimationID); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return details, nil
+}
+
+// OrderHistory list orders of the user with the total of order that matches the filter.
+// Pagination is applied on the orders, so a page always contains every matching item of its orders.
+func (repo *orderRepository) OrderHistory(userId string, params GetOrderHistQueryParams) ([]GetOrderHistQueryResult, int, *localError.GlobalError) {
 	orders := []GetOrderHistQueryResult{}
 
-	query := fmt.Sprintf(`
-	select 
-	o.id as order_id,
-	m.id as merchant_id ,
-	m.name as merchant_name,
-	m.merchant_category ,
-	m.image_url as merchant_image_url,
-	m.location_lat ,
-	m.location_long ,
-	m.created_at as merchant_created_at,
-	i.id as item_id,
-	i.name as item_name,
-	i.product_category ,
-	i.price ,
-	i.image_url as item_image_url,
-	i.created_at as item_created_at,
-	oei.quantity
-	from orders o inner join order_estimation oe 
-	on o.order_estimation_id = oe.id
-	inner join order_estimation_items oei 
-	on oe.id = oei.order_estimation_id
-	inner join items i 
-	on oei.item_id = i.id
-	inner join merchants m 
-	on i.merchant_id = m.id
-	where oe.user_id = '%s'`, userId)
+	// Condition of the listed items, order without any listed item is not listed
+	itemConditions := []string{}
+
+	filter := sqlbuilder.New("SELECT o.id, o.created_at FROM orders o INNER JOIN order_estimation oe ON o.order_estimation_id = oe.id")
+	filter.Where("oe.user_id = ?", userId)
 
 	if params.MerchantID != "" {
-		query += fmt.Sprintf(" AND m.id = '%s'", params.MerchantID)
+		itemConditions = append(itemConditions, "oei.merchant_id = "+filter.Arg(params.MerchantID))
 	}
 
 	if params.MerchantCategory == "SmallRestaurant" || params.MerchantCategory == "MediumRestaurant" || params.MerchantCategory == "LargeRestaurant" || params.MerchantCategory == "MerchandiseRestaurant" || params.MerchantCategory == "BoothKiosk" || params.MerchantCategory == "ConvenienceStore" {
-		query += fmt.Sprintf(" AND merchant_category = '%s'", params.MerchantCategory)
+		itemConditions = append(itemConditions, "oei.merchant_category = "+filter.Arg(params.MerchantCategory))
 	}
 
 	if params.Name != "" {
-		query += fmt.Sprintf(" AND (m.name ILIKE '%%%s%%' OR i.name ILIKE '%%%s%%')", params.Name, params.Name)
+		name := filter.Arg(sqlbuilder.Contains(params.Name))
+		itemConditions = append(itemConditions, fmt.Sprintf("(oei.merchant_name ILIKE %s OR oei.item_name ILIKE %s)", name, name))
 	}
 
+	itemCondition := "true"
+	if len(itemConditions) > 0 {
+		itemCondition = strings.Join(itemConditions, " AND ")
+		filter.Where(fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM order_estimation_items oei
+			WHERE oei.order_estimation_id = oe.id AND %s
+		)`, itemCondition))
+	}
+
+	// Count every matching order before pagination
+	var total int
+
+	countQ, countArgs := filter.Count()
+	if err := repo.db.Get(&total, countQ, countArgs...); err != nil {
+		return orders, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	// Cursor replaces the offset
+	offset := params.Offset
+	if params.Cursor != "" {
+		cursor, err := sqlbuilder.DecodeCursor(params.Cursor)
+		if err != nil {
+			return orders, 0, localError.ErrBadRequest(err.Error(), err)
+		}
+		filter.After(cursor, "o.created_at", "o.id", sqlbuilder.Desc)
+		offset = 0
+	}
+
+	filter.OrderBy("o.created_at", sqlbuilder.Desc).
+		OrderBy("o.id", sqlbuilder.Desc).
+		Paginate(params.Limit, offset)
+
+	// Keep rows of the same order & merchant together.
+	// Merchant and item data is rendered only from the snapshot of the estimation.
+	query := fmt.Sprintf(`
+	select 
+	o.id as order_id,
+	oei.merchant_id ,
+	oei.merchant_name,
+	oei.merchant_category ,
+	oei.merchant_image_url,
+	oei.merchant_location_lat as location_lat,
+	oei.merchant_location_long as location_long,
+	oei.merchant_created_at,
+	oei.item_id,
+	oei.item_name,
+	oei.item_product_category as product_category,
+	oei.item_price + oei.options_price as price,
+	oei.item_image_url,
+	oei.item_created_at,
+	oei.quantity,
+	oei.options,
+	o.status,
+	o.created_at as order_created_at
+	from (%s) p inner join orders o
+	on p.id = o.id
+	inner join order_estimation oe 
+	on o.order_estimation_id = oe.id
+	inner join order_estimation_items oei 
+	on oe.id = oei.order_estimation_id
+	where %s
+	order by p.created_at desc, o.id desc, oei.merchant_id`, filter.String(), itemCondition)
+
 	// log.Println(query)
 
-	err := repo.db.Select(&orders, query)
+	err := repo.db.Select(&orders, query, filter.Args()...)
 	if err != nil {
-		log.Println(err)
-		return orders, nil
+		return orders, 0, localError.ErrInternalServer(err.Error(), err)
 	}
 
-	return orders, nil
+	return orders, total, nil
 }
 
 func NewOrderRepository(db *sqlx.DB) IOrderRepository {
@@ -2,40 +2,140 @@ package purchase
 
 import (
 	"belimang/internal/merchant"
+	"belimang/internal/promotion"
 	"belimang/pkg/distances"
 	localError "belimang/pkg/error"
+	"belimang/pkg/sqlbuilder"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Default time window for user to cancel an order that has been accepted by merchant
+const defaultCancelWindow = 5 * time.Minute
+
+// Get cancellation window from environment variable.
+// ORDER_CANCEL_WINDOW_MINUTES should be a valid positive integer, otherwise default window is used.
+func getCancelWindow() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("ORDER_CANCEL_WINDOW_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultCancelWindow
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
+// Default maximum delivery area in km^2
+const defaultMaxAreaKm2 = 3
+
+// Get delivery area limit from environment variable.
+// DELIVERY_MAX_AREA_KM2 is the maximum area of the convex hull of user and merchants location,
+// DELIVERY_MAX_RADIUS_KM is the maximum distance of a merchant from user location (0 means no limit).
+func getGeofence() distances.Geofence {
+	geofence := distances.Geofence{
+		MaxAreaKm2: defaultMaxAreaKm2,
+	}
+
+	if area, err := strconv.ParseFloat(os.Getenv("DELIVERY_MAX_AREA_KM2"), 64); err == nil && area > 0 {
+		geofence.MaxAreaKm2 = area
+	}
+
+	if radius, err := strconv.ParseFloat(os.Getenv("DELIVERY_MAX_RADIUS_KM"), 64); err == nil && radius >= 0 {
+		geofence.MaxRadiusKm = radius
+	}
+
+	return geofence
+}
+
+// Default amount of merchants after the starting point that is routed exactly
+const defaultRouteExactMaxStops = 10
+
+// Get route solver option from environment variable.
+// ROUTE_EXACT_MAX_STOPS is the maximum amount of merchants (excluding the starting point) that is routed exactly,
+// ROUTE_SOLVER_DEADLINE_MS is the time budget of the heuristic solver for bigger order.
+func getRouteOptions() distances.RouteOptions {
+	opts := distances.RouteOptions{
+		ExactMaxStops: defaultRouteExactMaxStops,
+		Deadline:      distances.DefaultRouteDeadline,
+	}
+
+	if stops, err := strconv.Atoi(os.Getenv("ROUTE_EXACT_MAX_STOPS")); err == nil && stops > 0 {
+		opts.ExactMaxStops = stops
+	}
+
+	if ms, err := strconv.Atoi(os.Getenv("ROUTE_SOLVER_DEADLINE_MS")); err == nil && ms > 0 {
+		opts.Deadline = time.Duration(ms) * time.Millisecond
+	}
+
+	return opts
+}
+
+// Default lifetime of an order estimation before it can no longer be placed
+const defaultEstimateTTL = 15 * time.Minute
+
+// Get estimation lifetime from environment variable.
+// ESTIMATE_TTL_MINUTES should be a valid positive integer, otherwise default lifetime is used.
+func getEstimateTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("ESTIMATE_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultEstimateTTL
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
+
 type orderUsecase struct {
-	repo       IOrderRepository
-	merchantUc merchant.IMerchantUsecase
+	repo            IOrderRepository
+	idempotencyRepo IIdempotencyRepository
+	merchantUc      merchant.IMerchantUsecase
+	etaModel        IEtaModel
+	pricingEngine   IPricingEngine
+	promotionUc     promotion.IPromotionUsecase
 }
 
 type IOrderUsecase interface {
 	Estimate(dto Request) (*OrderEstimationResponse, *localError.GlobalError)
-	PlaceOrder(entity ActualOrder) (*ActualOrder, *localError.GlobalError)
-	OrderHistory(userId string, dto GetOrderHistQueryParams) ([]GetOrderHistResponseWithOrderId, *localError.GlobalError)
+	RoutePreview(dto Request) (*RoutePreviewResponse, *localError.GlobalError)
+	Reorder(userId string, orderId string, dto ReorderDTO) (*ReorderResponse, *localError.GlobalError)
+	PlaceOrder(userId string, entity ActualOrder) (*ActualOrder, *localError.GlobalError)
+	OrderHistory(userId string, dto GetOrderHistQueryParams) (*GetOrderHistResponseAndMeta, *localError.GlobalError)
+	UpdateOrderStatus(orderId string, changedBy string, dto UpdateOrderStatusDTO) (*OrderStatusResponse, *localError.GlobalError)
+	UpdateMerchantOrderStatus(merchantId string, orderId string, changedBy string, dto UpdateMerchantOrderStatusDTO) (*OrderStatusResponse, *localError.GlobalError)
+	CancelOrder(userId string, orderId string, dto CancelOrderDTO) (*OrderStatusResponse, *localError.GlobalError)
+	RejectOrder(orderId string, changedBy string, dto CancelOrderDTO) (*OrderStatusResponse, *localError.GlobalError)
+	ProcessRefund(orderId string, processedBy string) (*RefundResponse, *localError.GlobalError)
+	Receipt(userId string, orderId string) (*OrderReceiptResponse, *localError.GlobalError)
+	EstimationDetail(userId string, estimationId string) (*EstimationDetailResponse, *localError.GlobalError)
+	ReserveIdempotencyKey(entity IdempotencyKey) (*IdempotencyKey, *localError.GlobalError)
+	SaveIdempotencyResponse(entity IdempotencyKey) *localError.GlobalError
+	ReleaseIdempotencyKey(entity IdempotencyKey) *localError.GlobalError
+	ReleaseExpiredStock() (int64, *localError.GlobalError)
 }
 
-func NewOrderUsecase(repo IOrderRepository, mUc merchant.IMerchantUsecase) IOrderUsecase {
+func NewOrderUsecase(repo IOrderRepository, idempotencyRepo IIdempotencyRepository, mUc merchant.IMerchantUsecase, etaModel IEtaModel, pricingEngine IPricingEngine, promotionUc promotion.IPromotionUsecase) IOrderUsecase {
 	return &orderUsecase{
-		repo:       repo,
-		merchantUc: mUc,
+		repo:            repo,
+		idempotencyRepo: idempotencyRepo,
+		merchantUc:      mUc,
+		etaModel:        etaModel,
+		pricingEngine:   pricingEngine,
+		promotionUc:     promotionUc,
 	}
 }
 
 func (uc *orderUsecase) Estimate(dto Request) (*OrderEstimationResponse, *localError.GlobalError) {
-	// Create maps of points
-	// This maps is used to calculate area and distance
 	var (
-		points              []distances.Point
 		merchantIDs         []string
 		itemIDs             []string
-		estimationMerchants []OrderEstimationDetail
-		totalPrice          int
+		estimationItems     []OrderEstimationDetail
+		estimationMerchants []OrderEstimationMerchant
+		startingMerchantID  string
 	)
 
 	userPoint := distances.Point{
@@ -43,28 +143,25 @@ func (uc *orderUsecase) Estimate(dto Request) (*OrderEstimationResponse, *localE
 		Lat:  dto.UserLocation.Lat,
 		Long: dto.UserLocation.Long,
 	}
-	points = append(points, userPoint)
 
 	// Generate slice of merchant point
 	// Find merchant should use where In
-	// This block is also create quantity map
-	quantities := make(map[string]int)
+	seenItems := make(map[string]bool)
 	for _, v := range dto.Orders {
 		// Append ID Merchant to get checked later
 		merchantIDs = append(merchantIDs, v.MerchantID)
 
+		if v.IsStartingPoint {
+			startingMerchantID = v.MerchantID
+		}
+
 		// Loop to get Item IDs
+		// Same item can be ordered more than once with different options
 		for _, item := range v.Items {
-			itemIDs = append(itemIDs, item.ItemID)
-
-			// Add quantities by item ID
-			quantities[item.ItemID] = item.Quantity
-
-			// Append order estimation merchants
-			estimationMerchants = append(estimationMerchants, OrderEstimationDetail{
-				ItemID:   item.ItemID,
-				Quantity: item.Quantity,
-			})
+			if !seenItems[item.ItemID] {
+				seenItems[item.ItemID] = true
+				itemIDs = append(itemIDs, item.ItemID)
+			}
 		}
 	}
 
@@ -85,88 +182,556 @@ func (uc *orderUsecase) Estimate(dto Request) (*OrderEstimationResponse, *localE
 	}
 
 	// Loop merchant
+	merchantMap := make(map[string]merchant.Merchant)
 	for _, merchant := range merchants {
-		var merchantPoint distances.Point
+		merchantMap[merchant.ID] = merchant
+	}
 
-		merchantPoint.Lat = float64(merchant.LocationLat)
-		merchantPoint.Long = float64(merchant.LocationLong)
-		merchantPoint.Name = merchant.Name
+	itemMap := make(map[string]merchant.Item)
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
 
-		points = append(points, merchantPoint)
+	// Get option groups of the ordered items
+	optionGroups, err := uc.merchantUc.FindOptionGroups(itemIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	// Loop item to get total price
-	for _, item := range items {
-		totalPrice += quantities[item.ID] * item.Price
+	optionGroupMap := make(map[string][]merchant.OptionGroup)
+	for _, group := range optionGroups {
+		optionGroupMap[group.ItemID] = append(optionGroupMap[group.ItemID], group)
 	}
 
-	// Throw error if the area more than 3km^2
-	area := distances.CalculateArea(points)
+	// Snapshot ordered item with its merchant and get total price
+	for _, v := range dto.Orders {
+		for _, orderItem := range v.Items {
+			item := itemMap[orderItem.ItemID]
+			itemMerchant, exists := merchantMap[item.MerchantID]
+			if !exists || item.MerchantID != v.MerchantID {
+				return nil, localError.ErrNotFound("ID Merchant / Item not valid", fmt.Errorf("item %s is not owned by merchant %s", item.ID, v.MerchantID))
+			}
+
+			// Sold out item can not be ordered
+			if !item.IsAvailable {
+				message := fmt.Sprintf("Item not available: %s", item.Name)
+				return nil, localError.ErrBadRequest(message, fmt.Errorf("item %s is not available", item.ID))
+			}
+
+			options, err := selectItemOptions(item, optionGroupMap[item.ID], orderItem.OptionIDs)
+			if err != nil {
+				return nil, err
+			}
+
+			detail := OrderEstimationDetail{
+				ItemID:               item.ID,
+				Quantity:             orderItem.Quantity,
+				MerchantID:           itemMerchant.ID,
+				MerchantName:         itemMerchant.Name,
+				MerchantLocationLat:  itemMerchant.LocationLat,
+				MerchantLocationLong: itemMerchant.LocationLong,
+				ItemName:             item.Name,
+				ItemPrice:            item.Price,
+				Options:              options,
+				MerchantCategory:     itemMerchant.MerchantCategory,
+				MerchantImageUrl:     itemMerchant.ImageUrl,
+				MerchantCreatedAt:    itemMerchant.CreatedAt,
+				ProductCategory:      item.ProductCategory,
+				ItemImageUrl:         item.ImageUrl,
+				ItemCreatedAt:        item.CreatedAt,
+			}
+
+			for _, option := range options {
+				detail.OptionsPrice += option.PriceDelta
+			}
+
+			estimationItems = append(estimationItems, detail)
+		}
+	}
 
-	if area > 3*math.Pow10(6) {
-		return nil, localError.ErrBadRequest("Area too far", fmt.Errorf("area too far"))
+	// Items price of every merchant, used to charge small order fee
+	subtotals := []MerchantSubtotal{}
+	for _, v := range dto.Orders {
+		subtotal := MerchantSubtotal{
+			MerchantID: v.MerchantID,
+			Category:   merchantMap[v.MerchantID].MerchantCategory,
+		}
+
+		for _, detail := range estimationItems {
+			if detail.MerchantID == v.MerchantID {
+				subtotal.Subtotal += detail.Quantity * detail.UnitPrice()
+			}
+		}
+
+		subtotals = append(subtotals, subtotal)
 	}
 
-	// Permutate shortest distance
-	track, d := distances.ShortestDistance(points)
-	log.Println(track, d)
+	// Closed merchant can not prepare the order
+	if err := uc.merchantUc.CheckMerchantsOpen(merchantIDs, time.Now()); err != nil {
+		return nil, err
+	}
 
-	// Calculate fastest / shortest delivery time
-	var time float64
+	route, eta, err := uc.planDelivery(userPoint, merchants, startingMerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delivery fee follows the distance of the route
+	price := uc.pricingEngine.Price(subtotals, route.Distance)
 
-	for i := 0; i <= len(points)-2; i++ {
-		p1 := distances.Point{
-			Lat:  track[i].Lat,
-			Long: track[i].Long,
+	// Voucher is validated against the priced order, then its discount is deducted before tax
+	var discount *promotion.Discount
+	if dto.VoucherCode != "" {
+		cart := promotion.Cart{
+			UserID:      dto.UserId,
+			DeliveryFee: price.DeliveryFee,
+		}
+		for _, subtotal := range subtotals {
+			cart.Merchants = append(cart.Merchants, promotion.CartMerchant(subtotal))
 		}
 
-		p2 := distances.Point{
-			Lat:  track[i+1].Lat,
-			Long: track[i+1].Long,
+		discount, err = uc.promotionUc.Apply(dto.VoucherCode, cart, time.Now())
+		if err != nil {
+			return nil, err
 		}
 
-		twoPointDistance := distances.Calculate(distances.DistanceRaw{
-			Start: p1,
-			End:   p2,
-		})
+		price = uc.pricingEngine.ApplyDiscount(price, discount.Amount)
+	}
 
-		time += twoPointDistance / float64(DeliveryVelocity)
+	for i, legDistance := range route.Legs {
+		estimationMerchants = append(estimationMerchants, OrderEstimationMerchant{
+			MerchantID:      route.Stops[i].ID,
+			IsStartingPoint: route.Stops[i].ID == startingMerchantID,
+			VisitOrder:      i + 1,
+			LegDistance:     legDistance,
+			LegTime:         int(math.Round(eta.LegMinutes[i])),
+			SmallOrderFee:   price.SmallOrderFees[route.Stops[i].ID],
+		})
 	}
 
-	absTime := int(math.Round(time * 60))
+	absTime := int(math.Round(eta.TotalMinutes))
 
 	// Store user estimation
 	var estimation OrderEstimation = OrderEstimation{
 		UserID:        dto.UserId,
 		UserLat:       userPoint.Lat,
 		UserLong:      userPoint.Long,
-		Price:         totalPrice,
+		Price:         price.Total,
 		EstimatedTime: absTime,
+		ExpiresAt:     time.Now().Add(getEstimateTTL()),
+		ItemsPrice:    price.ItemsPrice,
+		DeliveryFee:   price.DeliveryFee,
+		SmallOrderFee: price.SmallOrderFee,
+		ServiceFee:    price.ServiceFee,
+		Tax:           price.Tax,
+		Discount:      price.Discount,
 	}
 
-	estimationID, err := uc.repo.CreateEstimation(&estimation)
-	if err != nil {
-		return nil, err
+	if discount != nil {
+		estimation.PromotionID = sql.NullString{String: discount.PromotionID, Valid: true}
+		estimation.VoucherCode = sql.NullString{String: discount.Code, Valid: true}
 	}
 
-	// Store order estimation merchants
-	err = uc.repo.CreateOrderMerchant(estimationID, estimationMerchants)
+	// Store the estimation with its items and merchants
+	estimationID, err := uc.repo.CreateEstimation(&estimation, estimationItems, estimationMerchants)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generate response
 	response := OrderEstimationResponse{
-		TotalPrice:                     totalPrice,
+		TotalPrice:                     estimation.Price,
+		PriceBreakdown:                 FormatPriceBreakdownResponse(&estimation),
 		EstimatedDeliveryTimeInMinutes: absTime,
 		CalculatedEstimateID:           estimationID,
+		ExpiresAt:                      estimation.ExpiresAt.Format(time.RFC3339),
 	}
 
 	return &response, nil
 }
 
-func (uc *orderUsecase) PlaceOrder(entity ActualOrder) (*ActualOrder, *localError.GlobalError) {
-	result, err := uc.repo.PlaceOrder(entity.OrderEstimationId)
+// planDelivery check the delivery area, then find the route that starts from the starting point merchant,
+// visits the other merchants and ends at user location, with the travel time of every leg
+func (uc *orderUsecase) planDelivery(userPoint distances.Point, merchants []merchant.Merchant, startingMerchantID string) (distances.Route, EtaResult, *localError.GlobalError) {
+	// Create slice of merchant points
+	// This slice is used to check delivery area and calculate distance
+	var (
+		points        distances.Vertex
+		startingPoint distances.Point
+		otherPoints   distances.Vertex
+	)
+
+	for _, merchant := range merchants {
+		merchantPoint := distances.Point{
+			ID:   merchant.ID,
+			Name: merchant.Name,
+			Lat:  float64(merchant.LocationLat),
+			Long: float64(merchant.LocationLong),
+		}
+
+		points = append(points, merchantPoint)
+
+		if merchant.ID == startingMerchantID {
+			startingPoint = merchantPoint
+		} else {
+			otherPoints = append(otherPoints, merchantPoint)
+		}
+	}
+
+	// Throw error if any merchant makes the delivery out of range
+	if violation := getGeofence().Check(userPoint, points); violation != nil {
+		message := fmt.Sprintf("Area too far: %s", violation.Reason)
+		return distances.Route{}, EtaResult{}, localError.ErrBadRequest(message, violation)
+	}
+
+	// Big order is solved by heuristic solver, so the estimation stays fast
+	route := distances.SolveRoute(startingPoint, otherPoints, userPoint, getRouteOptions())
+	if !route.Optimal {
+		log.Printf("route of %d merchants is solved by heuristic solver", len(otherPoints)+1)
+	}
+
+	// Preparation time of every merchant, merchant without one uses the ETA model default
+	preparation := make(map[string]int)
+	for _, m := range merchants {
+		if m.PreparationTime.Valid {
+			preparation[m.ID] = int(m.PreparationTime.Int32)
+		}
+	}
+
+	// Estimate travel time of every leg and total delivery time
+	eta := uc.etaModel.Estimate(route, preparation, time.Now())
+
+	return route, eta, nil
+}
+
+// RoutePreview show the delivery route of the order without storing an estimation
+func (uc *orderUsecase) RoutePreview(dto Request) (*RoutePreviewResponse, *localError.GlobalError) {
+	var (
+		merchantIDs        []string
+		startingMerchantID string
+	)
+
+	for _, v := range dto.Orders {
+		merchantIDs = append(merchantIDs, v.MerchantID)
+
+		if v.IsStartingPoint {
+			startingMerchantID = v.MerchantID
+		}
+	}
+
+	merchants, err := uc.merchantUc.CheckMerchantIDs(merchantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(merchants) != len(merchantIDs) {
+		return nil, localError.ErrNotFound("ID Merchant not valid", fmt.Errorf("merchant is invalid"))
+	}
+
+	userPoint := distances.Point{
+		Name: "user",
+		Lat:  dto.UserLocation.Lat,
+		Long: dto.UserLocation.Long,
+	}
+
+	route, eta, err := uc.planDelivery(userPoint, merchants, startingMerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := FormatRoutePreviewResponse(route, eta)
+
+	return &response, nil
+}
+
+// Reorder rebuild the estimate request from the items of a past order and estimate it again.
+// Item that can not be ordered anymore is dropped with a warning instead of failing the whole order.
+func (uc *orderUsecase) Reorder(userId string, orderId string, dto ReorderDTO) (*ReorderResponse, *localError.GlobalError) {
+	var (
+		merchantIDs        []string
+		itemIDs            []string
+		openIDs            []string
+		startingMerchantID string
+	)
+
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide order from another user
+	if order.UserID != userId {
+		return nil, localError.ErrNotFound("Order data not found", fmt.Errorf("order is not owned by user"))
+	}
+
+	details, err := uc.repo.FindEstimationItems(order.OrderEstimationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(details) == 0 {
+		return nil, localError.ErrUnprocessableEntity("Order has no item to reorder", fmt.Errorf("order %s has no item", orderId))
+	}
+
+	// Older order does not record its visited merchants, so it starts from the first merchant
+	visited, err := uc.repo.FindEstimationMerchants(order.OrderEstimationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range visited {
+		if v.IsStartingPoint {
+			startingMerchantID = v.MerchantID
+		}
+	}
+
+	seenMerchants := make(map[string]bool)
+	seenItems := make(map[string]bool)
+	for _, d := range details {
+		if !seenMerchants[d.MerchantID] {
+			seenMerchants[d.MerchantID] = true
+			merchantIDs = append(merchantIDs, d.MerchantID)
+		}
+
+		if !seenItems[d.ItemID] {
+			seenItems[d.ItemID] = true
+			itemIDs = append(itemIDs, d.ItemID)
+		}
+	}
+
+	// Current data of the merchants and items, deleted one is not returned
+	merchants, err := uc.merchantUc.CheckMerchantIDs(merchantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := uc.merchantUc.CheckItemIDs(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	optionGroups, err := uc.merchantUc.FindOptionGroups(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	merchantMap := make(map[string]merchant.Merchant)
+	for _, merchant := range merchants {
+		merchantMap[merchant.ID] = merchant
+		openIDs = append(openIDs, merchant.ID)
+	}
+
+	itemMap := make(map[string]merchant.Item)
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+
+	optionGroupMap := make(map[string][]merchant.OptionGroup)
+	for _, group := range optionGroups {
+		optionGroupMap[group.ItemID] = append(optionGroupMap[group.ItemID], group)
+	}
+
+	// Closed merchant can not prepare the order
+	closed := make(map[string]string)
+	if len(openIDs) > 0 {
+		closed, err = uc.merchantUc.FindClosedMerchants(openIDs, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	warnings := []string{}
+	warnedMerchants := make(map[string]bool)
+	orderIndex := make(map[string]int)
+	orders := []Order{}
+
+	for _, d := range details {
+		if _, exists := merchantMap[d.MerchantID]; !exists {
+			if !warnedMerchants[d.MerchantID] {
+				warnedMerchants[d.MerchantID] = true
+				warnings = append(warnings, fmt.Sprintf("Merchant is no longer available: %s", d.MerchantName))
+			}
+			continue
+		}
+
+		if reason, isClosed := closed[d.MerchantID]; isClosed {
+			if !warnedMerchants[d.MerchantID] {
+				warnedMerchants[d.MerchantID] = true
+				warnings = append(warnings, fmt.Sprintf("Merchant closed: %s", reason))
+			}
+			continue
+		}
+
+		item, exists := itemMap[d.ItemID]
+		if !exists || item.MerchantID != d.MerchantID {
+			warnings = append(warnings, fmt.Sprintf("Item is no longer available: %s", d.ItemName))
+			continue
+		}
+
+		if !item.IsAvailable {
+			warnings = append(warnings, fmt.Sprintf("Item not available: %s", item.Name))
+			continue
+		}
+
+		optionIDs := []string{}
+		for _, option := range d.Options {
+			optionIDs = append(optionIDs, option.OptionID)
+		}
+
+		// Options of the item may have changed since the past order
+		if _, err := selectItemOptions(item, optionGroupMap[item.ID], optionIDs); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Options are no longer available: %s", item.Name))
+			continue
+		}
+
+		ix, exists := orderIndex[d.MerchantID]
+		if !exists {
+			ix = len(orders)
+			orderIndex[d.MerchantID] = ix
+			orders = append(orders, Order{
+				MerchantID:      d.MerchantID,
+				IsStartingPoint: d.MerchantID == startingMerchantID,
+			})
+		}
+
+		orders[ix].Items = append(orders[ix].Items, Item{
+			ItemID:    item.ID,
+			Quantity:  d.Quantity,
+			OptionIDs: optionIDs,
+		})
+	}
+
+	// Delivery location may be different from the past order,
+	// merchant that makes the delivery out of range is dropped one by one
+	userPoint := distances.Point{
+		Name: "user",
+		Lat:  dto.UserLocation.Lat,
+		Long: dto.UserLocation.Long,
+	}
+
+	for len(orders) > 0 {
+		var points distances.Vertex
+		for _, o := range orders {
+			m := merchantMap[o.MerchantID]
+			points = append(points, distances.Point{
+				ID:   m.ID,
+				Name: m.Name,
+				Lat:  float64(m.LocationLat),
+				Long: float64(m.LocationLong),
+			})
+		}
+
+		violation := getGeofence().Check(userPoint, points)
+		if violation == nil {
+			break
+		}
+
+		warnings = append(warnings, fmt.Sprintf("Area too far: %s", violation.Reason))
+		orders = dropMerchantOrder(orders, violation.Point.ID)
+	}
+
+	// Stock is only known when it is reserved, so item without enough stock is dropped and estimated again
+	for len(orders) > 0 {
+		startFromFirstMerchant(orders)
+
+		estimate, err := uc.Estimate(Request{
+			UserId:       userId,
+			UserLocation: dto.UserLocation,
+			Orders:       orders,
+			VoucherCode:  dto.VoucherCode,
+		})
+		if err == nil {
+			return &ReorderResponse{
+				OrderEstimationResponse: *estimate,
+				Warnings:                warnings,
+			}, nil
+		}
+
+		var stockErr *InsufficientStockError
+		if !errors.As(err.Error, &stockErr) {
+			return nil, err
+		}
+
+		warnings = append(warnings, fmt.Sprintf("Item is out of stock: %s", stockErr.ItemName))
+		orders = dropOrderItem(orders, stockErr.ItemID)
+	}
+
+	message := fmt.Sprintf("None of the ordered items is available: %s", strings.Join(warnings, ", "))
+	return nil, localError.ErrUnprocessableEntity(message, fmt.Errorf("no item of order %s can be reordered", orderId))
+}
+
+// Remove the merchant and its items from the orders
+func dropMerchantOrder(orders []Order, merchantID string) []Order {
+	kept := []Order{}
+	for _, o := range orders {
+		if o.MerchantID != merchantID {
+			kept = append(kept, o)
+		}
+	}
+
+	return kept
+}
+
+// Remove every line of the item, merchant without any item left is removed too
+func dropOrderItem(orders []Order, itemID string) []Order {
+	kept := []Order{}
+	for _, o := range orders {
+		items := []Item{}
+		for _, item := range o.Items {
+			if item.ItemID != itemID {
+				items = append(items, item)
+			}
+		}
+
+		if len(items) > 0 {
+			o.Items = items
+			kept = append(kept, o)
+		}
+	}
+
+	return kept
+}
+
+// Starting point merchant may have been dropped, start from the first merchant that is left
+func startFromFirstMerchant(orders []Order) {
+	for _, o := range orders {
+		if o.IsStartingPoint {
+			return
+		}
+	}
+
+	orders[0].IsStartingPoint = true
+}
+
+// EstimationDetail show user estimation with the visited merchants and its route
+func (uc *orderUsecase) EstimationDetail(userId string, estimationId string) (*EstimationDetailResponse, *localError.GlobalError) {
+	estimation, err := uc.repo.FindEstimationById(estimationId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide estimation from another user
+	if estimation.UserID != userId {
+		return nil, localError.ErrNotFound("Estimation data not found", fmt.Errorf("estimation is not owned by user"))
+	}
+
+	merchants, err := uc.repo.FindEstimationMerchants(estimationId)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := uc.repo.FindEstimationItems(estimationId)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := FormatEstimationDetailResponse(estimation, merchants, details)
+
+	return &detail, nil
+}
+
+func (uc *orderUsecase) PlaceOrder(userId string, entity ActualOrder) (*ActualOrder, *localError.GlobalError) {
+	result, err := uc.repo.PlaceOrder(userId, entity.OrderEstimationId)
 	if err != nil {
 		return nil, err
 	}
@@ -176,35 +741,269 @@ func (uc *orderUsecase) PlaceOrder(entity ActualOrder) (*ActualOrder, *localErro
 	}, nil
 }
 
-func (uc *orderUsecase) OrderHistory(userId string, dto GetOrderHistQueryParams) ([]GetOrderHistResponseWithOrderId, *localError.GlobalError) {
-	orders, err := uc.repo.OrderHistory(userId, dto)
-  	if err != nil {
+func (uc *orderUsecase) OrderHistory(userId string, dto GetOrderHistQueryParams) (*GetOrderHistResponseAndMeta, *localError.GlobalError) {
+	orders, total, err := uc.repo.OrderHistory(userId, dto)
+	if err != nil {
 		return nil, err
 	}
-  
+
 	ordersWithOrderId := FormatGetOrderHistResponse(orders)
 
 	resp := FormatGetOrderHistResponseWithOrderId(ordersWithOrderId)
 
-	limit := 5
-	offset := 0
-	if dto.Limit != 0 {
-		limit = dto.Limit
+	// Rows are grouped by order, the last row belongs to the last order of the page
+	last := sqlbuilder.Cursor{}
+	if len(orders) > 0 {
+		last = sqlbuilder.Cursor{CreatedAt: orders[len(orders)-1].OrderCreatedAt, ID: orders[len(orders)-1].OrderId}
+	}
+
+	return &GetOrderHistResponseAndMeta{
+		Data: resp,
+		Meta: merchant.NewMeta(dto.Limit, dto.Offset, total).WithCursor(dto.Cursor, len(resp), last),
+	}, nil
+}
+
+// UpdateOrderStatus move the order through the lifecycle.
+// Only transition listed in the order transition table is allowed.
+func (uc *orderUsecase) UpdateOrderStatus(orderId string, changedBy string, dto UpdateOrderStatusDTO) (*OrderStatusResponse, *localError.GlobalError) {
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.updateStatus(order, changedBy, dto)
+}
+
+// UpdateMerchantOrderStatus advance the order on behalf of a merchant in the order
+func (uc *orderUsecase) UpdateMerchantOrderStatus(merchantId string, orderId string, changedBy string, dto UpdateMerchantOrderStatusDTO) (*OrderStatusResponse, *localError.GlobalError) {
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	merchants, err := uc.repo.FindEstimationMerchants(order.OrderEstimationID)
+	if err != nil {
+		return nil, err
 	}
-	if dto.Offset != 0 {
-		offset = dto.Offset
+
+	for _, m := range merchants {
+		if m.MerchantID == merchantId {
+			return uc.updateStatus(order, changedBy, UpdateOrderStatusDTO{
+				Status: dto.Status,
+				Note:   dto.Note,
+			})
+		}
 	}
 
-	if offset >= len(resp) {
-		return []GetOrderHistResponseWithOrderId{}, nil
+	// Order of another merchant is treated as not found
+	return nil, localError.ErrNotFound("Order data not found", fmt.Errorf("merchant %s is not in order %s", merchantId, orderId))
+}
+
+// Move the order to the next status if the transition is allowed
+func (uc *orderUsecase) updateStatus(order *PlacedOrder, changedBy string, dto UpdateOrderStatusDTO) (*OrderStatusResponse, *localError.GlobalError) {
+	if !order.Status.CanTransitionTo(dto.Status) {
+		message := fmt.Sprintf("Order status cannot be changed from %s to %s", order.Status, dto.Status)
+		return nil, localError.ErrConflict(message, fmt.Errorf("invalid status transition"))
 	}
-	if limit < 0 {
-		return []GetOrderHistResponseWithOrderId{}, nil
+
+	// Cancelled order always comes with refund
+	if dto.Status == OrderCancelled {
+		return uc.cancel(order, changedBy, dto.Note)
 	}
-	if offset+limit > len(resp) {
-		cut := offset+limit-len(resp)
-		limit = limit-cut
+
+	if err := uc.repo.UpdateOrderStatus(order, dto.Status, changedBy, dto.Note); err != nil {
+		return nil, err
 	}
-	
-	return resp[offset:offset+limit], nil
-}
\ No newline at end of file
+
+	return &OrderStatusResponse{
+		OrderId:   order.ID,
+		Status:    order.Status,
+		UpdatedAt: order.UpdatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// CancelOrder cancel user own order.
+// Placed order can be cancelled anytime, while accepted order can only be cancelled
+// inside the cancellation window. Order that is being prepared cannot be cancelled.
+func (uc *orderUsecase) CancelOrder(userId string, orderId string, dto CancelOrderDTO) (*OrderStatusResponse, *localError.GlobalError) {
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide order from another user
+	if order.UserID != userId {
+		return nil, localError.ErrNotFound("Order data not found", fmt.Errorf("order is not owned by user"))
+	}
+
+	switch order.Status {
+	case OrderPlaced:
+	case OrderAccepted:
+		window := getCancelWindow()
+		if time.Since(order.CreatedAt) > window {
+			message := fmt.Sprintf("Accepted order can only be cancelled within %d minutes after it is placed", int(window.Minutes()))
+			return nil, localError.ErrConflict(message, fmt.Errorf("cancellation window exceeded"))
+		}
+	default:
+		message := fmt.Sprintf("Order with status %s cannot be cancelled", order.Status)
+		return nil, localError.ErrConflict(message, fmt.Errorf("invalid status transition"))
+	}
+
+	return uc.cancel(order, userId, dto.Reason)
+}
+
+// RejectOrder is used by merchant side to refuse the order
+func (uc *orderUsecase) RejectOrder(orderId string, changedBy string, dto CancelOrderDTO) (*OrderStatusResponse, *localError.GlobalError) {
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.Status.CanTransitionTo(OrderCancelled) {
+		message := fmt.Sprintf("Order with status %s cannot be rejected", order.Status)
+		return nil, localError.ErrConflict(message, fmt.Errorf("invalid status transition"))
+	}
+
+	reason := dto.Reason
+	if reason == "" {
+		reason = "Rejected by merchant"
+	}
+
+	return uc.cancel(order, changedBy, reason)
+}
+
+// ProcessRefund mark the refund as processed after the money is returned to the user
+func (uc *orderUsecase) ProcessRefund(orderId string, processedBy string) (*RefundResponse, *localError.GlobalError) {
+	refund, err := uc.repo.ProcessRefund(orderId, processedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return FormatRefundResponse(refund), nil
+}
+
+// Receipt of user order, rendered from the estimation snapshot
+func (uc *orderUsecase) Receipt(userId string, orderId string) (*OrderReceiptResponse, *localError.GlobalError) {
+	order, err := uc.repo.FindOrderById(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide order from another user
+	if order.UserID != userId {
+		return nil, localError.ErrNotFound("Order data not found", fmt.Errorf("order is not owned by user"))
+	}
+
+	estimation, err := uc.repo.FindEstimationById(order.OrderEstimationID)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := uc.repo.FindEstimationItems(order.OrderEstimationID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := FormatOrderReceiptResponse(order, estimation, details)
+
+	return &receipt, nil
+}
+
+// Cancel the order and generate the refund response
+func (uc *orderUsecase) cancel(order *PlacedOrder, changedBy string, reason string) (*OrderStatusResponse, *localError.GlobalError) {
+	refund, err := uc.repo.CancelOrder(order, changedBy, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderStatusResponse{
+		OrderId:   order.ID,
+		Status:    order.Status,
+		UpdatedAt: order.UpdatedAt.Format(time.RFC3339),
+		Refund:    FormatRefundResponse(refund),
+	}, nil
+}
+
+// ReserveIdempotencyKey reserve the key for the first request.
+// For retried request, the stored key with its response is returned.
+func (uc *orderUsecase) ReserveIdempotencyKey(entity IdempotencyKey) (*IdempotencyKey, *localError.GlobalError) {
+	stored, reserved, err := uc.idempotencyRepo.Reserve(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved {
+		return nil, nil
+	}
+
+	if stored.RequestHash != entity.RequestHash {
+		return nil, localError.ErrUnprocessableEntity("Idempotency key is already used for a different request", fmt.Errorf("idempotency key payload mismatch"))
+	}
+
+	if !stored.ResponseCode.Valid {
+		return nil, localError.ErrConflict("Request with the same idempotency key is being processed", fmt.Errorf("idempotency key is in progress"))
+	}
+
+	return stored, nil
+}
+
+func (uc *orderUsecase) SaveIdempotencyResponse(entity IdempotencyKey) *localError.GlobalError {
+	return uc.idempotencyRepo.SaveResponse(entity)
+}
+
+func (uc *orderUsecase) ReleaseIdempotencyKey(entity IdempotencyKey) *localError.GlobalError {
+	return uc.idempotencyRepo.Release(entity)
+}
+
+// Validate selected options of the ordered item against its option groups.
+// Every group must be selected between its minimum and maximum selection.
+func selectItemOptions(item merchant.Item, groups []merchant.OptionGroup, optionIDs []string) (OrderItemOptions, *localError.GlobalError) {
+	selected := OrderItemOptions{}
+	selectedIDs := make(map[string]bool)
+
+	for _, id := range optionIDs {
+		if selectedIDs[id] {
+			message := fmt.Sprintf("Option of %s is selected more than once", item.Name)
+			return nil, localError.ErrBadRequest(message, fmt.Errorf("option %s is duplicated", id))
+		}
+		selectedIDs[id] = true
+	}
+
+	for _, group := range groups {
+		count := 0
+
+		for _, option := range group.Options {
+			if !selectedIDs[option.ID] {
+				continue
+			}
+
+			count++
+			delete(selectedIDs, option.ID)
+
+			selected = append(selected, OrderItemOption{
+				OptionGroupID:   group.ID,
+				OptionGroupName: group.Name,
+				OptionID:        option.ID,
+				Name:            option.Name,
+				PriceDelta:      option.PriceDelta,
+			})
+		}
+
+		if count < group.MinSelect || count > group.MaxSelect {
+			message := fmt.Sprintf("%s of %s should be selected between %d and %d options", group.Name, item.Name, group.MinSelect, group.MaxSelect)
+			return nil, localError.ErrBadRequest(message, fmt.Errorf("option group %s has %d selected options", group.ID, count))
+		}
+	}
+
+	// Option that is left doesn't belong to the item
+	for id := range selectedIDs {
+		return nil, localError.ErrNotFound("Option not valid", fmt.Errorf("option %s is not an option of item %s", id, item.ID))
+	}
+
+	return selected, nil
+}
+
+// ReleaseExpiredStock give back stock reserved by expired estimations
+func (uc *orderUsecase) ReleaseExpiredStock() (int64, *localError.GlobalError) {
+	return uc.repo.ReleaseExpiredStock()
+}
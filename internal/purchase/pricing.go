@@ -0,0 +1,248 @@
+package purchase
+
+import (
+	"belimang/internal/merchant"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Fee rule of a merchant category
+type FeeRule struct {
+	DeliveryBaseFee   int
+	DeliveryFeePerKm  int
+	ServiceFeePercent float64 // Percentage of the merchant subtotal
+	SmallOrderMinimum int     // Merchant subtotal below this is charged SmallOrderFee
+	SmallOrderFee     int
+}
+
+type PricingConfig struct {
+	TaxPercent float64 // Percentage of the items price and every fee after discount
+	Default    FeeRule
+	Categories map[merchant.MerchantCategories]FeeRule // Override Default for the category
+}
+
+// Items price of a merchant in the order
+type MerchantSubtotal struct {
+	MerchantID string
+	Category   merchant.MerchantCategories
+	Subtotal   int
+}
+
+// Itemised price of an order
+type PriceBreakdown struct {
+	ItemsPrice     int
+	DeliveryFee    int
+	SmallOrderFees map[string]int // Small order fee of every merchant ID
+	SmallOrderFee  int            // Sum of SmallOrderFees
+	ServiceFee     int
+	Discount       int // Discount of the applied voucher, deducted before tax
+	Tax            int
+	Total          int
+}
+
+type IPricingEngine interface {
+	// Price the order with the merchants subtotal and the delivery route distance in km
+	Price(subtotals []MerchantSubtotal, distanceKm float64) PriceBreakdown
+	// Deduct the discount from the price and recalculate the tax
+	ApplyDiscount(breakdown PriceBreakdown, discount int) PriceBreakdown
+}
+
+type pricingEngine struct {
+	config PricingConfig
+}
+
+func NewPricingEngine(config PricingConfig) IPricingEngine {
+	if config.Categories == nil {
+		config.Categories = make(map[merchant.MerchantCategories]FeeRule)
+	}
+
+	return &pricingEngine{
+		config: config,
+	}
+}
+
+// Load pricing config from environment variable, every fee is 0 when it is not set
+//
+//	DELIVERY_FEE_BASE             flat delivery fee
+//	DELIVERY_FEE_PER_KM           delivery fee of every km of the route
+//	SERVICE_FEE_PERCENT           service fee in percent of the items price
+//	TAX_PERCENT                   tax in percent of the items price and fees
+//	SMALL_ORDER_MINIMUM           merchant subtotal below this is charged SMALL_ORDER_FEE
+//	SMALL_ORDER_FEE               small order fee of every merchant
+//	PRICING_CATEGORY_RULES        fee rules of merchant categories, see parseCategoryRules
+func LoadPricingConfig() PricingConfig {
+	config := PricingConfig{}
+
+	if fee, err := strconv.Atoi(os.Getenv("DELIVERY_FEE_BASE")); err == nil && fee >= 0 {
+		config.Default.DeliveryBaseFee = fee
+	}
+
+	if fee, err := strconv.Atoi(os.Getenv("DELIVERY_FEE_PER_KM")); err == nil && fee >= 0 {
+		config.Default.DeliveryFeePerKm = fee
+	}
+
+	if percent, err := strconv.ParseFloat(os.Getenv("SERVICE_FEE_PERCENT"), 64); err == nil && percent >= 0 {
+		config.Default.ServiceFeePercent = percent
+	}
+
+	if percent, err := strconv.ParseFloat(os.Getenv("TAX_PERCENT"), 64); err == nil && percent >= 0 {
+		config.TaxPercent = percent
+	}
+
+	if minimum, err := strconv.Atoi(os.Getenv("SMALL_ORDER_MINIMUM")); err == nil && minimum >= 0 {
+		config.Default.SmallOrderMinimum = minimum
+	}
+
+	if fee, err := strconv.Atoi(os.Getenv("SMALL_ORDER_FEE")); err == nil && fee >= 0 {
+		config.Default.SmallOrderFee = fee
+	}
+
+	categories, err := parseCategoryRules(os.Getenv("PRICING_CATEGORY_RULES"), config.Default)
+	if err != nil {
+		log.Fatalf("invalid PRICING_CATEGORY_RULES: %s", err.Error())
+	}
+	config.Categories = categories
+
+	return config
+}
+
+// Parse fee rules of merchant categories, rules of a category are separated by semicolon.
+// Fee that is not set uses the default rule.
+//
+//	"BoothKiosk:smallOrderMinimum=10000,smallOrderFee=2000;LargeRestaurant:deliveryFeePerKm=3000,serviceFeePercent=3"
+//
+// Fee keys are deliveryBaseFee, deliveryFeePerKm, serviceFeePercent, smallOrderMinimum and smallOrderFee.
+// Invalid rule is returned as error, so a typo doesn't silently charge the default fee.
+func parseCategoryRules(raw string, defaultRule FeeRule) (map[merchant.MerchantCategories]FeeRule, error) {
+	rules := make(map[merchant.MerchantCategories]FeeRule)
+
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		category, fees, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid pricing category rule %s", part)
+		}
+
+		if !merchant.MerchantCategories(category).IsValid() {
+			return nil, fmt.Errorf("unknown merchant category %s in pricing rule", category)
+		}
+
+		rule := defaultRule
+		for _, fee := range strings.Split(fees, ",") {
+			key, value, found := strings.Cut(strings.TrimSpace(fee), "=")
+			if !found || !setFeeRule(&rule, key, value) {
+				return nil, fmt.Errorf("invalid pricing rule %s of %s", fee, category)
+			}
+		}
+
+		rules[merchant.MerchantCategories(category)] = rule
+	}
+
+	return rules, nil
+}
+
+// Set a fee of the rule by its key, return false if the key or value is not valid
+func setFeeRule(rule *FeeRule, key string, value string) bool {
+	if key == "serviceFeePercent" {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil || percent < 0 {
+			return false
+		}
+
+		rule.ServiceFeePercent = percent
+		return true
+	}
+
+	amount, err := strconv.Atoi(value)
+	if err != nil || amount < 0 {
+		return false
+	}
+
+	switch key {
+	case "deliveryBaseFee":
+		rule.DeliveryBaseFee = amount
+	case "deliveryFeePerKm":
+		rule.DeliveryFeePerKm = amount
+	case "smallOrderMinimum":
+		rule.SmallOrderMinimum = amount
+	case "smallOrderFee":
+		rule.SmallOrderFee = amount
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Fee rule of the merchant category, category without a rule uses the default rule
+func (p *pricingEngine) ruleOf(category merchant.MerchantCategories) FeeRule {
+	if rule, exists := p.config.Categories[category]; exists {
+		return rule
+	}
+
+	return p.config.Default
+}
+
+// Percentage of the amount, rounded to the nearest integer
+func percentOf(amount int, percent float64) int {
+	return int(math.Round(float64(amount) * percent / 100))
+}
+
+// Service and small order fee are charged on every merchant subtotal by the rule of its category.
+// The order is delivered in a single trip, so the delivery fee uses the highest delivery rule of its merchants.
+func (p *pricingEngine) Price(subtotals []MerchantSubtotal, distanceKm float64) PriceBreakdown {
+	breakdown := PriceBreakdown{
+		SmallOrderFees: make(map[string]int),
+	}
+
+	deliveryBaseFee, deliveryFeePerKm := 0, 0
+	if len(subtotals) == 0 {
+		deliveryBaseFee, deliveryFeePerKm = p.config.Default.DeliveryBaseFee, p.config.Default.DeliveryFeePerKm
+	}
+
+	for _, s := range subtotals {
+		rule := p.ruleOf(s.Category)
+
+		breakdown.ItemsPrice += s.Subtotal
+		breakdown.ServiceFee += percentOf(s.Subtotal, rule.ServiceFeePercent)
+
+		if s.Subtotal < rule.SmallOrderMinimum {
+			breakdown.SmallOrderFees[s.MerchantID] = rule.SmallOrderFee
+			breakdown.SmallOrderFee += rule.SmallOrderFee
+		}
+
+		deliveryBaseFee = max(deliveryBaseFee, rule.DeliveryBaseFee)
+		deliveryFeePerKm = max(deliveryFeePerKm, rule.DeliveryFeePerKm)
+	}
+
+	breakdown.DeliveryFee = deliveryBaseFee + int(math.Round(distanceKm*float64(deliveryFeePerKm)))
+
+	return p.withTax(breakdown)
+}
+
+func (p *pricingEngine) ApplyDiscount(breakdown PriceBreakdown, discount int) PriceBreakdown {
+	breakdown.Discount = discount
+
+	return p.withTax(breakdown)
+}
+
+// Calculate tax and total of the discounted price
+func (p *pricingEngine) withTax(breakdown PriceBreakdown) PriceBreakdown {
+	beforeTax := breakdown.ItemsPrice + breakdown.DeliveryFee + breakdown.SmallOrderFee + breakdown.ServiceFee - breakdown.Discount
+	if beforeTax < 0 {
+		beforeTax = 0
+	}
+
+	breakdown.Tax = percentOf(beforeTax, p.config.TaxPercent)
+	breakdown.Total = beforeTax + breakdown.Tax
+
+	return breakdown
+}
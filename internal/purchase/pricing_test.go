@@ -0,0 +1,148 @@
+package purchase
+
+import (
+	"belimang/internal/merchant"
+	"reflect"
+	"testing"
+)
+
+func TestPricingEnginePrice(t *testing.T) {
+	engine := NewPricingEngine(PricingConfig{
+		TaxPercent: 10,
+		Default: FeeRule{
+			DeliveryBaseFee:   5000,
+			DeliveryFeePerKm:  2000,
+			ServiceFeePercent: 2,
+			SmallOrderMinimum: 20000,
+			SmallOrderFee:     3000,
+		},
+		Categories: map[merchant.MerchantCategories]FeeRule{
+			merchant.BoothKiosk: {
+				DeliveryBaseFee:   3000,
+				DeliveryFeePerKm:  1000,
+				ServiceFeePercent: 0,
+				SmallOrderMinimum: 10000,
+				SmallOrderFee:     1000,
+			},
+			merchant.LargeRestaurant: {
+				DeliveryBaseFee:   8000,
+				DeliveryFeePerKm:  2500,
+				ServiceFeePercent: 5,
+				SmallOrderMinimum: 50000,
+				SmallOrderFee:     4000,
+			},
+		},
+	})
+
+	tests := []struct {
+		name      string
+		subtotals []MerchantSubtotal
+		distance  float64
+		want      PriceBreakdown
+	}{
+		{
+			name:      "category without rule uses default",
+			subtotals: []MerchantSubtotal{{MerchantID: "m1", Category: merchant.SmallRestaurant, Subtotal: 15000}},
+			distance:  2,
+			want: PriceBreakdown{
+				ItemsPrice:     15000,
+				DeliveryFee:    9000,
+				SmallOrderFees: map[string]int{"m1": 3000},
+				SmallOrderFee:  3000,
+				ServiceFee:     300,
+				Tax:            2730,
+				Total:          30030,
+			},
+		},
+		{
+			name:      "category rule overrides every fee",
+			subtotals: []MerchantSubtotal{{MerchantID: "m1", Category: merchant.BoothKiosk, Subtotal: 15000}},
+			distance:  2,
+			want: PriceBreakdown{
+				ItemsPrice:     15000,
+				DeliveryFee:    5000,
+				SmallOrderFees: map[string]int{},
+				Tax:            2000,
+				Total:          22000,
+			},
+		},
+		{
+			name: "merchants are charged by their own category and delivery uses the highest rule",
+			subtotals: []MerchantSubtotal{
+				{MerchantID: "m1", Category: merchant.BoothKiosk, Subtotal: 8000},
+				{MerchantID: "m2", Category: merchant.LargeRestaurant, Subtotal: 60000},
+			},
+			distance: 2,
+			want: PriceBreakdown{
+				ItemsPrice:     68000,
+				DeliveryFee:    13000,
+				SmallOrderFees: map[string]int{"m1": 1000},
+				SmallOrderFee:  1000,
+				ServiceFee:     3000,
+				Tax:            8500,
+				Total:          93500,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.Price(tt.subtotals, tt.distance)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("price is %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCategoryRules(t *testing.T) {
+	defaultRule := FeeRule{
+		DeliveryBaseFee:   5000,
+		DeliveryFeePerKm:  2000,
+		ServiceFeePercent: 2,
+		SmallOrderMinimum: 20000,
+		SmallOrderFee:     3000,
+	}
+
+	raw := "BoothKiosk:smallOrderMinimum=10000,smallOrderFee=2000; LargeRestaurant:deliveryBaseFee=8000,serviceFeePercent=3.5"
+
+	want := map[merchant.MerchantCategories]FeeRule{
+		merchant.BoothKiosk: {
+			DeliveryBaseFee:   5000,
+			DeliveryFeePerKm:  2000,
+			ServiceFeePercent: 2,
+			SmallOrderMinimum: 10000,
+			SmallOrderFee:     2000,
+		},
+		merchant.LargeRestaurant: {
+			DeliveryBaseFee:   8000,
+			DeliveryFeePerKm:  2000,
+			ServiceFeePercent: 3.5,
+			SmallOrderMinimum: 20000,
+			SmallOrderFee:     3000,
+		},
+	}
+
+	got, err := parseCategoryRules(raw, defaultRule)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rules are %+v, want %+v", got, want)
+	}
+
+	invalid := []string{
+		"SmallRestaurnt:smallOrderFee=2000",
+		"BoothKiosk",
+		"BoothKiosk:smallOrderFe=2000",
+		"BoothKiosk:smallOrderFee=-1",
+	}
+
+	for _, raw := range invalid {
+		if _, err := parseCategoryRules(raw, defaultRule); err == nil {
+			t.Errorf("rule %q should be rejected", raw)
+		}
+	}
+}
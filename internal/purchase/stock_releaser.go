@@ -0,0 +1,47 @@
+package purchase
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Default interval to release stock of expired estimation
+const defaultStockReleaseInterval = time.Minute
+
+// Get stock release interval from environment variable.
+// STOCK_RELEASE_INTERVAL_SECONDS should be a valid positive integer, otherwise default interval is used.
+func getStockReleaseInterval() time.Duration {
+	seconds, err := strconv.Atoi(os.Getenv("STOCK_RELEASE_INTERVAL_SECONDS"))
+	if err != nil || seconds <= 0 {
+		return defaultStockReleaseInterval
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// RunStockReleaser periodically give back stock reserved by expired estimations
+// until the context is done
+func RunStockReleaser(ctx context.Context, uc IOrderUsecase) {
+	ticker := time.NewTicker(getStockReleaseInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := uc.ReleaseExpiredStock()
+			if err != nil {
+				log.Printf("failed to release expired stock: %v", err.Message)
+				continue
+			}
+
+			if released > 0 {
+				log.Printf("released %d stock reservations of expired estimations", released)
+			}
+		}
+	}
+}
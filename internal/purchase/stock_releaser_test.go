@@ -0,0 +1,51 @@
+package purchase
+
+import (
+	localError "belimang/pkg/error"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Order usecase that only counts the stock release
+type releaseCounter struct {
+	IOrderUsecase
+	calls atomic.Int32
+}
+
+func (r *releaseCounter) ReleaseExpiredStock() (int64, *localError.GlobalError) {
+	r.calls.Add(1)
+	return 0, nil
+}
+
+func TestRunStockReleaserStopsOnCancel(t *testing.T) {
+	t.Setenv("STOCK_RELEASE_INTERVAL_SECONDS", "1")
+
+	uc := &releaseCounter{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		RunStockReleaser(ctx, uc)
+		close(done)
+	}()
+
+	// Wait for the first release, then stop the releaser
+	deadline := time.Now().Add(3 * time.Second)
+	for uc.calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if uc.calls.Load() == 0 {
+		t.Fatal("stock is never released")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("releaser is still running after the context is cancelled")
+	}
+}
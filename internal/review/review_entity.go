@@ -0,0 +1,97 @@
+package review
+
+import (
+	"belimang/internal/merchant"
+	"belimang/internal/purchase"
+	"time"
+)
+
+// Review of a merchant in a delivered order, one per merchant per order
+type Review struct {
+	ID         string       `db:"id"`
+	OrderID    string       `db:"order_id"`
+	MerchantID string       `db:"merchant_id"`
+	UserID     string       `db:"user_id"`
+	Rating     int          `db:"rating"`
+	Comment    string       `db:"comment"`
+	CreatedAt  time.Time    `db:"created_at"`
+	Items      []ItemReview `db:"-"`
+}
+
+// Rating of an ordered item of the reviewed merchant
+type ItemReview struct {
+	ReviewID string `db:"review_id"`
+	ItemID   string `db:"item_id"`
+	Rating   int    `db:"rating"`
+}
+
+// Item of the merchant in an order with the order owner and status
+type OrderedItem struct {
+	UserID string               `db:"user_id"`
+	Status purchase.OrderStatus `db:"status"`
+	ItemID string               `db:"item_id"`
+}
+
+type ItemReviewDTO struct {
+	ItemID string `json:"itemId" binding:"required,uuid"`
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+}
+
+type CreateReviewDTO struct {
+	MerchantID string          `json:"merchantId" binding:"required,uuid"`
+	Rating     int             `json:"rating" binding:"required,min=1,max=5"`
+	Comment    string          `json:"comment" binding:"max=500"`
+	Items      []ItemReviewDTO `json:"items" binding:"dive"`
+}
+
+type GetReviewQueryParams struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+type ItemReviewResponse struct {
+	ItemID string `json:"itemId"`
+	Rating int    `json:"rating"`
+}
+
+type ReviewResponse struct {
+	ReviewID   string               `json:"reviewId"`
+	OrderID    string               `json:"orderId"`
+	MerchantID string               `json:"merchantId"`
+	Rating     int                  `json:"rating"`
+	Comment    string               `json:"comment"`
+	Items      []ItemReviewResponse `json:"items"`
+	CreatedAt  string               `json:"createdAt"`
+}
+
+type ReviewResponseAndMeta struct {
+	Data []ReviewResponse `json:"data"`
+	Meta merchant.Meta    `json:"meta"`
+}
+
+func FormatReviewResponse(reviews []Review) []ReviewResponse {
+	response := []ReviewResponse{}
+
+	for _, r := range reviews {
+		row := ReviewResponse{
+			ReviewID:   r.ID,
+			OrderID:    r.OrderID,
+			MerchantID: r.MerchantID,
+			Rating:     r.Rating,
+			Comment:    r.Comment,
+			Items:      []ItemReviewResponse{},
+			CreatedAt:  r.CreatedAt.Format(time.RFC3339),
+		}
+
+		for _, item := range r.Items {
+			row.Items = append(row.Items, ItemReviewResponse{
+				ItemID: item.ItemID,
+				Rating: item.Rating,
+			})
+		}
+
+		response = append(response, row)
+	}
+
+	return response
+}
@@ -0,0 +1,73 @@
+package review
+
+import (
+	"belimang/internal/middleware"
+	"belimang/internal/user"
+	"belimang/pkg/response"
+	"belimang/pkg/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reviewHandler struct {
+	uc IReviewUsecase
+}
+
+func NewReviewHandler(uc IReviewUsecase) *reviewHandler {
+	return &reviewHandler{
+		uc: uc,
+	}
+}
+
+func (h *reviewHandler) Router(r *gin.RouterGroup) {
+	userGroup := r.Group("", middleware.UseJwtAuth, middleware.HasRoles(string(user.USER)))
+
+	userGroup.POST("/users/orders/:orderId/reviews", middleware.ValidateUUIDParam("orderId", "Order data not found"), h.CreateReview)
+	userGroup.GET("/merchants/:merchantId/reviews", middleware.ValidateUUIDParam("merchantId", "Merchant data not found"), h.FindMerchantReviews)
+}
+
+func (h *reviewHandler) CreateReview(c *gin.Context) {
+	var request CreateReviewDTO
+
+	userId := c.GetString("userID")
+	orderId := c.Param("orderId")
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	resp, err := h.uc.CreateReview(userId, orderId, request)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusCreated, response.WithData(*resp))
+}
+
+func (h *reviewHandler) FindMerchantReviews(c *gin.Context) {
+	var query GetReviewQueryParams
+
+	merchantId := c.Param("merchantId")
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		res := validation.FormatValidation(err)
+		response.GenerateResponse(c, res.Code, response.WithMessage(res.Message))
+		c.Abort()
+		return
+	}
+
+	reviews, err := h.uc.FindMerchantReviews(merchantId, query)
+	if err != nil {
+		response.GenerateResponse(c, err.Code, response.WithMessage(err.Message))
+		c.Abort()
+		return
+	}
+
+	response.GenerateResponseReturnData(c, http.StatusOK, response.WithMessage("Review fetched successfully!"), response.WithData(reviews))
+}
@@ -0,0 +1,157 @@
+package review
+
+import (
+	localError "belimang/pkg/error"
+	"belimang/pkg/sqlbuilder"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IReviewRepository interface {
+	FindOrderedItems(orderId string, merchantId string) ([]OrderedItem, *localError.GlobalError)
+	CreateReview(entity *Review) *localError.GlobalError
+	FindMerchantReviews(merchantId string, params GetReviewQueryParams) ([]Review, int, *localError.GlobalError)
+}
+
+type reviewRepository struct {
+	db *sqlx.DB
+}
+
+func NewReviewRepository(db *sqlx.DB) IReviewRepository {
+	return &reviewRepository{
+		db: db,
+	}
+}
+
+// Get items of the merchant in the order, empty if the merchant is not in the order
+func (r *reviewRepository) FindOrderedItems(orderId string, merchantId string) ([]OrderedItem, *localError.GlobalError) {
+	items := []OrderedItem{}
+
+	q := `
+		SELECT oe.user_id, o.status, oei.item_id
+		FROM orders o
+		INNER JOIN order_estimation oe ON o.order_estimation_id = oe.id
+		INNER JOIN order_estimation_items oei ON oei.order_estimation_id = oe.id
+		WHERE o.id = $1 AND oei.merchant_id = $2
+	`
+
+	if err := r.db.Select(&items, q, orderId, merchantId); err != nil {
+		return nil, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return items, nil
+}
+
+// Store the review with its item ratings and update the rating aggregate in a single transaction.
+// Aggregate is updated incrementally on the locked row, so concurrent reviews are not lost.
+func (r *reviewRepository) CreateReview(entity *Review) *localError.GlobalError {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+	defer tx.Rollback()
+
+	q := `
+		INSERT INTO merchant_reviews (order_id, merchant_id, user_id, rating, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id, merchant_id) DO NOTHING
+		RETURNING id, created_at
+	`
+
+	err = tx.QueryRowx(q, entity.OrderID, entity.MerchantID, entity.UserID, entity.Rating, entity.Comment).Scan(&entity.ID, &entity.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return localError.ErrConflict("Merchant has already been reviewed for this order", err)
+		}
+
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	aggregateQ := `
+		UPDATE %s SET
+			rating_average = (rating_average * rating_count + $1) / (rating_count + 1),
+			rating_count = rating_count + 1
+		WHERE id = $2
+	`
+
+	if _, err := tx.Exec(fmt.Sprintf(aggregateQ, "merchants"), entity.Rating, entity.MerchantID); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	for i, item := range entity.Items {
+		entity.Items[i].ReviewID = entity.ID
+
+		itemQ := "INSERT INTO item_reviews (review_id, item_id, rating) VALUES ($1, $2, $3)"
+		if _, err := tx.Exec(itemQ, entity.ID, item.ItemID, item.Rating); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf(aggregateQ, "items"), item.Rating, item.ItemID); err != nil {
+			return localError.ErrInternalServer(err.Error(), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return localError.ErrInternalServer(err.Error(), err)
+	}
+
+	return nil
+}
+
+// FindMerchantReviews list the newest reviews of the merchant with the total of its reviews
+func (r *reviewRepository) FindMerchantReviews(merchantId string, params GetReviewQueryParams) ([]Review, int, *localError.GlobalError) {
+	reviews := []Review{}
+
+	query := sqlbuilder.New("SELECT * FROM merchant_reviews")
+	query.Where("merchant_id = ?", merchantId)
+
+	var total int
+
+	countQ, countArgs := query.Count()
+	if err := r.db.Get(&total, countQ, countArgs...); err != nil {
+		return reviews, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	query.OrderBy("created_at", sqlbuilder.Desc).
+		OrderBy("id", sqlbuilder.Desc).
+		Paginate(params.Limit, params.Offset)
+
+	q, args := query.Build()
+
+	if err := r.db.Select(&reviews, q, args...); err != nil {
+		return reviews, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	if len(reviews) == 0 {
+		return reviews, total, nil
+	}
+
+	// Attach item ratings of the reviews on the page
+	reviewIndex := make(map[string]int)
+	reviewIDs := []string{}
+	for i, review := range reviews {
+		reviewIndex[review.ID] = i
+		reviewIDs = append(reviewIDs, review.ID)
+		reviews[i].Items = []ItemReview{}
+	}
+
+	itemQ, itemArgs, err := sqlx.In("SELECT * FROM item_reviews WHERE review_id in (?) ORDER BY review_id, item_id", reviewIDs)
+	if err != nil {
+		return reviews, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	items := []ItemReview{}
+	if err := r.db.Select(&items, r.db.Rebind(itemQ), itemArgs...); err != nil {
+		return reviews, 0, localError.ErrInternalServer(err.Error(), err)
+	}
+
+	for _, item := range items {
+		ix := reviewIndex[item.ReviewID]
+		reviews[ix].Items = append(reviews[ix].Items, item)
+	}
+
+	return reviews, total, nil
+}
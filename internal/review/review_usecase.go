@@ -0,0 +1,98 @@
+package review
+
+import (
+	"belimang/internal/merchant"
+	"belimang/internal/purchase"
+	localError "belimang/pkg/error"
+	"fmt"
+)
+
+type IReviewUsecase interface {
+	CreateReview(userId string, orderId string, req CreateReviewDTO) (*ReviewResponse, *localError.GlobalError)
+	FindMerchantReviews(merchantId string, params GetReviewQueryParams) (ReviewResponseAndMeta, *localError.GlobalError)
+}
+
+type reviewUsecase struct {
+	repo       IReviewRepository
+	merchantUc merchant.IMerchantUsecase
+}
+
+func NewReviewUsecase(repo IReviewRepository, mUc merchant.IMerchantUsecase) IReviewUsecase {
+	return &reviewUsecase{
+		repo:       repo,
+		merchantUc: mUc,
+	}
+}
+
+// CreateReview review a merchant of a delivered order of the user.
+// Rated items should be ordered from the reviewed merchant in the same order.
+func (uc *reviewUsecase) CreateReview(userId string, orderId string, req CreateReviewDTO) (*ReviewResponse, *localError.GlobalError) {
+	ordered, err := uc.repo.FindOrderedItems(orderId, req.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order of another user is treated as not found
+	if len(ordered) == 0 || ordered[0].UserID != userId {
+		return nil, localError.ErrNotFound("Order data not found", fmt.Errorf("merchant %s is not in order %s of the user", req.MerchantID, orderId))
+	}
+
+	if ordered[0].Status != purchase.OrderDelivered {
+		return nil, localError.ErrUnprocessableEntity("Only delivered order can be reviewed", fmt.Errorf("order %s is %s", orderId, ordered[0].Status))
+	}
+
+	orderedItems := make(map[string]bool)
+	for _, item := range ordered {
+		orderedItems[item.ItemID] = true
+	}
+
+	review := Review{
+		OrderID:    orderId,
+		MerchantID: req.MerchantID,
+		UserID:     userId,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+		Items:      []ItemReview{},
+	}
+
+	rated := make(map[string]bool)
+	for _, item := range req.Items {
+		if !orderedItems[item.ItemID] {
+			return nil, localError.ErrBadRequest("Item is not ordered from the merchant", fmt.Errorf("item %s is not in order %s", item.ItemID, orderId))
+		}
+
+		if rated[item.ItemID] {
+			return nil, localError.ErrBadRequest("Item can only be rated once", fmt.Errorf("item %s is rated more than once", item.ItemID))
+		}
+		rated[item.ItemID] = true
+
+		review.Items = append(review.Items, ItemReview{
+			ItemID: item.ItemID,
+			Rating: item.Rating,
+		})
+	}
+
+	if err := uc.repo.CreateReview(&review); err != nil {
+		return nil, err
+	}
+
+	response := FormatReviewResponse([]Review{review})[0]
+
+	return &response, nil
+}
+
+func (uc *reviewUsecase) FindMerchantReviews(merchantId string, params GetReviewQueryParams) (ReviewResponseAndMeta, *localError.GlobalError) {
+	if _, err := uc.merchantUc.FindMerchantById(merchantId); err != nil {
+		return ReviewResponseAndMeta{}, err
+	}
+
+	reviews, total, err := uc.repo.FindMerchantReviews(merchantId, params)
+	if err != nil {
+		return ReviewResponseAndMeta{}, err
+	}
+
+	return ReviewResponseAndMeta{
+		Data: FormatReviewResponse(reviews),
+		Meta: merchant.NewMeta(params.Limit, params.Offset, total),
+	}, nil
+}
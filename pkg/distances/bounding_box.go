@@ -0,0 +1,46 @@
+package distances
+
+import (
+	"belimang/pkg/helper"
+	"math"
+)
+
+// Rectangle of lat / long that contains every point within a radius of its center.
+// It is used to pre-filter rows with a plain index before the exact distance is calculated.
+type BoundingBox struct {
+	MinLat  float64
+	MaxLat  float64
+	MinLong float64
+	MaxLong float64
+}
+
+// Bounding box of every point within radiusKm of the center.
+// When the box covers a pole or crosses the antimeridian, the whole longitude range is used.
+func NewBoundingBox(center Point, radiusKm float64) BoundingBox {
+	// Angular radius in degrees
+	dLat := radiusKm / EARTH_RADIUS * 180 / math.Pi
+
+	box := BoundingBox{
+		MinLat:  center.Lat - dLat,
+		MaxLat:  center.Lat + dLat,
+		MinLong: -180,
+		MaxLong: 180,
+	}
+
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat = math.Max(box.MinLat, -90)
+		box.MaxLat = math.Min(box.MaxLat, 90)
+		return box
+	}
+
+	// Longitude degree gets shorter away from the equator
+	dLong := math.Asin(math.Sin(helper.ToRad(dLat))/math.Cos(helper.ToRad(center.Lat))) * 180 / math.Pi
+	if center.Long-dLong < -180 || center.Long+dLong > 180 {
+		return box
+	}
+
+	box.MinLong = center.Long - dLong
+	box.MaxLong = center.Long + dLong
+
+	return box
+}
@@ -0,0 +1,63 @@
+package distances
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Connect to the migrated database configured by DB_* environment variables
+func connectTestDb(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		t.Skip("DB_HOST is not set, calculate_distance is only checked against a migrated database")
+	}
+
+	connection := fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?%s",
+		os.Getenv("DB_USERNAME"),
+		os.Getenv("DB_PASSWORD"),
+		host,
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME"),
+		os.Getenv("DB_PARAMS"),
+	)
+
+	db, err := sqlx.Connect("postgres", connection)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// calculate_distance SQL function must match the reference distance and distances.Calculate
+func TestCalculateDistanceSqlParity(t *testing.T) {
+	db := connectTestDb(t)
+
+	for _, tt := range distanceFixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			var got float64
+
+			q := "SELECT calculate_distance($1, $2, $3, $4)"
+			if err := db.Get(&got, q, tt.start.Lat, tt.start.Long, tt.end.Lat, tt.end.Long); err != nil {
+				t.Fatalf("failed to calculate distance: %v", err)
+			}
+
+			if math.Abs(got-tt.want) > distanceTolerance {
+				t.Errorf("SQL distance is %f, want %f", got, tt.want)
+			}
+
+			if goDistance := Calculate(DistanceRaw{Start: tt.start, End: tt.end}); math.Abs(got-goDistance) > distanceTolerance {
+				t.Errorf("SQL distance is %f, Go distance is %f", got, goDistance)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package distances
 import (
 	"belimang/pkg/helper"
 	"math"
+	"sort"
 )
 
 type DistanceRaw struct {
@@ -11,6 +12,7 @@ type DistanceRaw struct {
 }
 
 type Point struct {
+	ID   string
 	Name string
 	Lat  float64
 	Long float64
@@ -18,78 +20,116 @@ type Point struct {
 
 type Vertex []Point
 
-func CalculateArea(points Vertex) float64 {
-	var area float64
+// Planar coordinate in km, projected around a reference latitude
+type planar struct {
+	X float64
+	Y float64
+}
 
-	// Area is valid if data has more than 2 point
-	if len(points) > 2 {
-		for i := 0; i < len(points)-1; i++ {
-			var (
-				p1, p2 Point
-			)
-			// Check if the loop is reaching max element
-			p1 = points[i]
-
-			if i < len(points)-1 {
-				p2 = points[i+1]
-			} else {
-				p2 = points[0]
-			}
-
-			area += helper.ToRad(p2.Long-p1.Long) * (2 + math.Sin(helper.ToRad(p1.Lat)) + math.Sin(helper.ToRad(p2.Lat)))
-		}
+// Project points to a local flat plane in km using equirectangular projection.
+// It is accurate enough for delivery area which is only a few km wide.
+func project(points Vertex) []planar {
+	if len(points) == 0 {
+		return nil
+	}
 
-		area = EARTH_RADIUS * area * EARTH_RADIUS / 2
+	var refLat, refLong float64
+	for _, p := range points {
+		refLat += p.Lat
+		refLong += p.Long
 	}
+	refLat /= float64(len(points))
+	refLong /= float64(len(points))
+
+	cosLat := math.Cos(helper.ToRad(refLat))
 
-	return area
+	result := make([]planar, len(points))
+	for i, p := range points {
+		result[i] = planar{
+			X: EARTH_RADIUS * helper.ToRad(p.Long-refLong) * cosLat,
+			Y: EARTH_RADIUS * helper.ToRad(p.Lat-refLat),
+		}
+	}
+
+	return result
 }
 
-// Distance between two point
-func pointDistance(p1, p2 Point) float64 {
-	dx := p2.Long - p1.Long
-	dy := p2.Lat - p1.Lat
-	return math.Sqrt(float64(dx*dx + dy*dy))
+// Cross product of OA and OB vector
+func cross(o, a, b planar) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
 }
 
-func (t Vertex) distance() float64 {
-	total := 0.0
-	for i := 0; i < len(t)-1; i++ {
-		total += pointDistance(t[i], t[i+1])
+// ConvexHull return the points that build the convex hull in counter clockwise order.
+// It is using monotone chain algorithm.
+func ConvexHull(points Vertex) Vertex {
+	if len(points) < 3 {
+		return append(Vertex{}, points...)
 	}
-	// total += pointDistance(t[len(t)-1], t[0]) // return to start
-	return total
-}
 
-func ShortestDistance(points Vertex) (Vertex, float64) {
-	shortest := points
-	shortestDistance := points.distance()
-
-	// Generate permutation
-	var generate func([]Point, int)
-
-	generate = func(p []Point, i int) {
-		if i == 1 {
-			d := Vertex(p).distance()
-
-			if d < shortestDistance && p[0].Name == "user" {
-				shortest = append(Vertex(nil), p...)
-				shortestDistance = d
-			}
-		} else {
-			for i := 0; i < i-1; i++ {
-				generate(p, i-1)
-				if i%2 == 0 {
-					p[i], p[i-1] = p[i-1], p[i]
-				} else {
-					p[0], p[i-1] = p[i-1], p[0]
-				}
-			}
-			generate(p, i-1)
+	projected := project(points)
+
+	index := make([]int, len(points))
+	for i := range index {
+		index[i] = i
+	}
+
+	sort.Slice(index, func(i, j int) bool {
+		a, b := projected[index[i]], projected[index[j]]
+		if a.X == b.X {
+			return a.Y < b.Y
+		}
+
+		return a.X < b.X
+	})
+
+	hull := make([]int, 0, 2*len(points))
+
+	// Lower hull
+	for _, i := range index {
+		for len(hull) >= 2 && cross(projected[hull[len(hull)-2]], projected[hull[len(hull)-1]], projected[i]) <= 0 {
+			hull = hull[:len(hull)-1]
 		}
+		hull = append(hull, i)
 	}
 
-	generate(points, len(points))
+	// Upper hull
+	lower := len(hull) + 1
+	for k := len(index) - 2; k >= 0; k-- {
+		i := index[k]
+		for len(hull) >= lower && cross(projected[hull[len(hull)-2]], projected[hull[len(hull)-1]], projected[i]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, i)
+	}
+
+	// Last point is the same as the first point
+	hull = hull[:len(hull)-1]
+
+	result := Vertex{}
+	for _, i := range hull {
+		result = append(result, points[i])
+	}
+
+	return result
+}
+
+// CalculateArea return the area of the convex hull of the points in km^2
+func CalculateArea(points Vertex) float64 {
+	hull := ConvexHull(points)
+
+	// Area is valid if data has more than 2 point
+	if len(hull) < 3 {
+		return 0
+	}
+
+	projected := project(hull)
+
+	// Shoelace formula, including the closing edge
+	var area float64
+	for i := range projected {
+		j := (i + 1) % len(projected)
+		area += projected[i].X*projected[j].Y - projected[j].X*projected[i].Y
+	}
 
-	return shortest, shortestDistance
+	return math.Abs(area) / 2
 }
@@ -0,0 +1,87 @@
+package distances
+
+import "fmt"
+
+// Delivery area limit.
+// Zero value of a limit means the limit is not checked.
+type Geofence struct {
+	MaxAreaKm2  float64 // Maximum area of the convex hull of the center and every point
+	MaxRadiusKm float64 // Maximum distance of every point from the center
+}
+
+// Point that makes the delivery out of the geofence
+type GeofenceViolation struct {
+	Point      Point
+	AreaKm2    float64
+	DistanceKm float64
+	Reason     string
+}
+
+func (v GeofenceViolation) Error() string {
+	return v.Reason
+}
+
+// Check if every point is inside the geofence of the center.
+// If it is not, the point that pushes the delivery out of range is returned.
+func (g Geofence) Check(center Point, points Vertex) *GeofenceViolation {
+	// Check the farthest point from the center
+	if g.MaxRadiusKm > 0 {
+		var (
+			farthest   Point
+			farthestKm float64
+		)
+
+		for _, p := range points {
+			d := Calculate(DistanceRaw{Start: center, End: p})
+			if d > farthestKm {
+				farthest = p
+				farthestKm = d
+			}
+		}
+
+		if farthestKm > g.MaxRadiusKm {
+			return &GeofenceViolation{
+				Point:      farthest,
+				DistanceKm: farthestKm,
+				Reason: fmt.Sprintf(
+					"%s is %.2f km away from delivery location, maximum distance is %.2f km",
+					farthest.Name, farthestKm, g.MaxRadiusKm,
+				),
+			}
+		}
+	}
+
+	if g.MaxAreaKm2 <= 0 {
+		return nil
+	}
+
+	area := CalculateArea(append(Vertex{center}, points...))
+	if area <= g.MaxAreaKm2 {
+		return nil
+	}
+
+	// The point that reduces the area the most when it is removed
+	// is the one that pushes the delivery out of range
+	culprit := 0
+	smallest := area
+
+	for i := range points {
+		others := append(Vertex{center}, points[:i]...)
+		others = append(others, points[i+1:]...)
+
+		if a := CalculateArea(others); a < smallest {
+			culprit = i
+			smallest = a
+		}
+	}
+
+	return &GeofenceViolation{
+		Point:      points[culprit],
+		AreaKm2:    area,
+		DistanceKm: Calculate(DistanceRaw{Start: center, End: points[culprit]}),
+		Reason: fmt.Sprintf(
+			"delivery area is %.2f km2, maximum area is %.2f km2, %s pushes the order out of range",
+			area, g.MaxAreaKm2, points[culprit].Name,
+		),
+	}
+}
@@ -0,0 +1,21 @@
+package distances
+
+// GeoJSON LineString geometry, every coordinate is [long, lat] as defined by RFC 7946
+type LineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+// LineString of the route that goes through every stop in order
+func (r Route) LineString() LineString {
+	line := LineString{
+		Type:        "LineString",
+		Coordinates: [][2]float64{},
+	}
+
+	for _, stop := range r.Stops {
+		line.Coordinates = append(line.Coordinates, [2]float64{stop.Long, stop.Lat})
+	}
+
+	return line
+}
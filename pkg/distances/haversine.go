@@ -7,6 +7,9 @@ import (
 
 const EARTH_RADIUS float64 = 6371.009
 
+// Great circle distance in km between the start and the end point.
+// calculate_distance SQL function uses the same formula and earth radius, keep them in sync.
+// Both are checked against the same reference distances in the tests.
 func Calculate(raw DistanceRaw) float64 {
 	// Calculate degree difference of lat and long
 	// Then convert the value to radians
@@ -20,7 +23,7 @@ func Calculate(raw DistanceRaw) float64 {
 	s := math.Pow(math.Sin(dLat/2), 2) + (math.Pow(math.Sin(dLong/2), 2) * math.Cos(lat1) * math.Cos(lat2))
 
 	// 2nd step
-	a := 2 * math.Asin(math.Sqrt(s))
+	a := 2 * math.Asin(math.Min(1, math.Sqrt(s)))
 
 	return a * EARTH_RADIUS
 }
@@ -0,0 +1,46 @@
+package distances
+
+import (
+	"math"
+	"testing"
+)
+
+// Maximum difference from the reference distance in km
+const distanceTolerance = 1e-6
+
+// Reference great circle distance in km with EARTH_RADIUS, computed independently with the atan2 (Vincenty) form.
+// distances.Calculate and calculate_distance SQL function must both match these.
+var distanceFixtures = []struct {
+	name  string
+	start Point
+	end   Point
+	want  float64
+}{
+	{name: "same point", start: Point{Lat: -6.1754, Long: 106.8272}, end: Point{Lat: -6.1754, Long: 106.8272}, want: 0},
+	{name: "antipodal on the equator", start: Point{Lat: 0, Long: 0}, end: Point{Lat: 0, Long: 180}, want: 20015.115070},
+	{name: "antipodal", start: Point{Lat: 10, Long: 20}, end: Point{Lat: -10, Long: -160}, want: 20015.115070},
+	{name: "pole to pole", start: Point{Lat: 90, Long: 0}, end: Point{Lat: -90, Long: 0}, want: 20015.115070},
+	{name: "across the 180 line on the equator", start: Point{Lat: 0, Long: 179.5}, end: Point{Lat: 0, Long: -179.5}, want: 111.195084},
+	{name: "across the 180 line near Fiji", start: Point{Lat: -16.5, Long: 179.9}, end: Point{Lat: -16.5, Long: -179.9}, want: 21.323207},
+	{name: "one degree of latitude", start: Point{Lat: 0, Long: 0}, end: Point{Lat: 1, Long: 0}, want: 111.195084},
+	{name: "Monas to Bundaran HI", start: Point{Lat: -6.1754, Long: 106.8272}, end: Point{Lat: -6.1950, Long: 106.8230}, want: 2.228332},
+	{name: "Blok M to Senayan", start: Point{Lat: -6.2444, Long: 106.8000}, end: Point{Lat: -6.2250, Long: 106.8016}, want: 2.164423},
+	{name: "across the street", start: Point{Lat: -6.2000, Long: 106.8166}, end: Point{Lat: -6.2001, Long: 106.8167}, want: 0.015679},
+}
+
+func TestCalculate(t *testing.T) {
+	for _, tt := range distanceFixtures {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Calculate(DistanceRaw{Start: tt.start, End: tt.end})
+			if math.Abs(got-tt.want) > distanceTolerance {
+				t.Errorf("distance is %f, want %f", got, tt.want)
+			}
+
+			// Distance doesn't depend on the direction
+			reversed := Calculate(DistanceRaw{Start: tt.end, End: tt.start})
+			if math.Abs(reversed-got) > distanceTolerance {
+				t.Errorf("reversed distance is %f, want %f", reversed, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package distances
+
+import (
+	"errors"
+	"math"
+)
+
+// Maximum amount of stops between start and end point that is solved exactly.
+// Held-Karp grows by 2^n * n^2, so bigger route should use another solver.
+const MaxExactStops = 12
+
+var ErrTooManyStops = errors.New("too many stops to be solved exactly")
+
+// Delivery route from the start point to the end point
+type Route struct {
+	Stops    Vertex    // Ordered points, including start and end point
+	Legs     []float64 // Distance of every leg in km, Legs[i] is from Stops[i] to Stops[i+1]
+	Distance float64   // Total distance in km
+	Optimal  bool      // True if the route is guaranteed to be the shortest one
+}
+
+// Generate haversine distance between every point
+func distanceMatrix(points Vertex) [][]float64 {
+	matrix := make([][]float64, len(points))
+
+	for i := range points {
+		matrix[i] = make([]float64, len(points))
+	}
+
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			d := Calculate(DistanceRaw{
+				Start: points[i],
+				End:   points[j],
+			})
+
+			matrix[i][j] = d
+			matrix[j][i] = d
+		}
+	}
+
+	return matrix
+}
+
+// Build route from ordered points
+func NewRoute(stops Vertex) Route {
+	route := Route{
+		Stops: stops,
+		Legs:  []float64{},
+	}
+
+	for i := 0; i < len(stops)-1; i++ {
+		leg := Calculate(DistanceRaw{
+			Start: stops[i],
+			End:   stops[i+1],
+		})
+
+		route.Legs = append(route.Legs, leg)
+		route.Distance += leg
+	}
+
+	return route
+}
+
+// ShortestRoute find the shortest route that starts from start point,
+// visits every stop exactly once and ends at end point.
+// The route is solved exactly using Held-Karp dynamic programming.
+func ShortestRoute(start Point, stops Vertex, end Point) (Route, error) {
+	n := len(stops)
+	if n > MaxExactStops {
+		return Route{}, ErrTooManyStops
+	}
+
+	// Node 0 is start point, node 1..n is the stops, node n+1 is end point
+	nodes := append(append(Vertex{start}, stops...), end)
+	matrix := distanceMatrix(nodes)
+
+	if n == 0 {
+		route := NewRoute(Vertex{start, end})
+		route.Optimal = true
+
+		return route, nil
+	}
+
+	full := 1<<n - 1
+
+	// cost[mask][j] is the shortest distance from start point,
+	// visiting every stop in mask and ending at stop j.
+	// parent[mask][j] is the stop visited before j.
+	cost := make([][]float64, full+1)
+	parent := make([][]int, full+1)
+
+	for mask := range cost {
+		cost[mask] = make([]float64, n)
+		parent[mask] = make([]int, n)
+
+		for j := range cost[mask] {
+			cost[mask][j] = math.Inf(1)
+			parent[mask][j] = -1
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		cost[1<<j][j] = matrix[0][j+1]
+	}
+
+	for mask := 1; mask <= full; mask++ {
+		for j := 0; j < n; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(cost[mask][j], 1) {
+				continue
+			}
+
+			for k := 0; k < n; k++ {
+				if mask&(1<<k) != 0 {
+					continue
+				}
+
+				next := mask | 1<<k
+				d := cost[mask][j] + matrix[j+1][k+1]
+
+				if d < cost[next][k] {
+					cost[next][k] = d
+					parent[next][k] = j
+				}
+			}
+		}
+	}
+
+	// Close the route to the end point
+	last := 0
+	shortest := math.Inf(1)
+
+	for j := 0; j < n; j++ {
+		d := cost[full][j] + matrix[j+1][n+1]
+
+		if d < shortest {
+			shortest = d
+			last = j
+		}
+	}
+
+	// Walk back from the last stop to get the visiting order
+	order := make([]int, 0, n)
+	mask := full
+
+	for j := last; j != -1; {
+		order = append(order, j)
+		prev := parent[mask][j]
+		mask &^= 1 << j
+		j = prev
+	}
+
+	ordered := Vertex{start}
+	for i := len(order) - 1; i >= 0; i-- {
+		ordered = append(ordered, stops[order[i]])
+	}
+	ordered = append(ordered, end)
+
+	route := NewRoute(ordered)
+	route.Optimal = true
+
+	return route, nil
+}
@@ -0,0 +1,200 @@
+package distances
+
+import (
+	"time"
+)
+
+// Default time budget for the heuristic solver to improve the route
+const DefaultRouteDeadline = 200 * time.Millisecond
+
+// Minimum improvement in km to be accepted, used to avoid endless loop caused by float rounding
+const improvementThreshold = 1e-9
+
+// Option of the route solver
+type RouteOptions struct {
+	ExactMaxStops int           // Route with more stops than this is solved by heuristic solver
+	Deadline      time.Duration // Time budget of heuristic solver
+}
+
+// SolveRoute find the shortest route from start point, visiting every stop and ending at end point.
+// Small route is solved exactly, while bigger route is solved by heuristic solver under the deadline.
+// Route.Optimal reports whether the result is guaranteed to be the shortest route.
+func SolveRoute(start Point, stops Vertex, end Point, opts RouteOptions) Route {
+	exactMaxStops := opts.ExactMaxStops
+	if exactMaxStops <= 0 || exactMaxStops > MaxExactStops {
+		exactMaxStops = MaxExactStops
+	}
+
+	if len(stops) <= exactMaxStops {
+		route, err := ShortestRoute(start, stops, end)
+		if err == nil {
+			return route
+		}
+	}
+
+	deadline := opts.Deadline
+	if deadline <= 0 {
+		deadline = DefaultRouteDeadline
+	}
+
+	return HeuristicRoute(start, stops, end, time.Now().Add(deadline))
+}
+
+// HeuristicRoute build the route using nearest neighbour,
+// then improve it with 2-opt and or-opt until no improvement is found or the deadline is reached.
+func HeuristicRoute(start Point, stops Vertex, end Point, deadline time.Time) Route {
+	n := len(stops)
+
+	// Node 0 is start point, node 1..n is the stops, node n+1 is end point
+	nodes := append(append(Vertex{start}, stops...), end)
+	matrix := distanceMatrix(nodes)
+
+	tour := nearestNeighbourTour(matrix, n)
+
+	for time.Now().Before(deadline) {
+		improved := twoOpt(tour, matrix, deadline)
+		improved = orOpt(tour, matrix, deadline) || improved
+
+		if !improved {
+			break
+		}
+	}
+
+	ordered := Vertex{}
+	for _, node := range tour {
+		ordered = append(ordered, nodes[node])
+	}
+
+	route := NewRoute(ordered)
+
+	// Route with one stop or less only has one possible order
+	route.Optimal = n <= 1
+
+	return route
+}
+
+// Visit the nearest unvisited stop until every stop is visited.
+// Tour always starts from node 0 and ends at node n+1.
+func nearestNeighbourTour(matrix [][]float64, n int) []int {
+	tour := []int{0}
+	visited := make([]bool, n+2)
+
+	current := 0
+	for len(tour) <= n {
+		next := -1
+
+		for k := 1; k <= n; k++ {
+			if visited[k] {
+				continue
+			}
+
+			if next == -1 || matrix[current][k] < matrix[current][next] {
+				next = k
+			}
+		}
+
+		visited[next] = true
+		tour = append(tour, next)
+		current = next
+	}
+
+	return append(tour, n+1)
+}
+
+// Reverse part of the tour when it makes the tour shorter.
+// First and last node of the tour is never moved.
+func twoOpt(tour []int, matrix [][]float64, deadline time.Time) bool {
+	improved := false
+	last := len(tour) - 2
+
+	for i := 1; i < last; i++ {
+		if time.Now().After(deadline) {
+			return improved
+		}
+
+		for j := i + 1; j <= last; j++ {
+			before := matrix[tour[i-1]][tour[i]] + matrix[tour[j]][tour[j+1]]
+			after := matrix[tour[i-1]][tour[j]] + matrix[tour[i]][tour[j+1]]
+
+			if after-before < -improvementThreshold {
+				for l, r := i, j; l < r; l, r = l+1, r-1 {
+					tour[l], tour[r] = tour[r], tour[l]
+				}
+
+				improved = true
+			}
+		}
+	}
+
+	return improved
+}
+
+// Move segment of one to three stops to another position of the tour
+// (optionally reversed) when it makes the tour shorter.
+func orOpt(tour []int, matrix [][]float64, deadline time.Time) bool {
+	improved := false
+	last := len(tour) - 2
+
+	for segment := 1; segment <= 3; segment++ {
+		for i := 1; i+segment-1 <= last; i++ {
+			if time.Now().After(deadline) {
+				return improved
+			}
+
+			j := i + segment - 1
+			prev, next := tour[i-1], tour[j+1]
+			first, end := tour[i], tour[j]
+
+			removeGain := matrix[prev][first] + matrix[end][next] - matrix[prev][next]
+
+			for k := 0; k <= last; k++ {
+				// Insert between tour[k] and tour[k+1], outside of the segment
+				if k >= i-1 && k <= j {
+					continue
+				}
+
+				a, b := tour[k], tour[k+1]
+				insertCost := matrix[a][first] + matrix[end][b] - matrix[a][b]
+				reversedCost := matrix[a][end] + matrix[first][b] - matrix[a][b]
+
+				reversed := reversedCost < insertCost
+				if reversed {
+					insertCost = reversedCost
+				}
+
+				if insertCost-removeGain < -improvementThreshold {
+					moveSegment(tour, i, j, k, reversed)
+					improved = true
+
+					break
+				}
+			}
+		}
+	}
+
+	return improved
+}
+
+// Move tour[i..j] to be placed after tour[k]
+func moveSegment(tour []int, i, j, k int, reversed bool) {
+	segment := append([]int{}, tour[i:j+1]...)
+	if reversed {
+		for l, r := 0, len(segment)-1; l < r; l, r = l+1, r-1 {
+			segment[l], segment[r] = segment[r], segment[l]
+		}
+	}
+
+	rest := append(append([]int{}, tour[:i]...), tour[j+1:]...)
+
+	// Position of tour[k] inside the rest of the tour
+	position := k
+	if k > j {
+		position = k - len(segment)
+	}
+
+	result := append([]int{}, rest[:position+1]...)
+	result = append(result, segment...)
+	result = append(result, rest[position+1:]...)
+
+	copy(tour, result)
+}
@@ -0,0 +1,233 @@
+package distances
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// Merchants around central Jakarta, a few km from each other
+var testStops = Vertex{
+	{ID: "m1", Lat: -6.2000, Long: 106.8166},
+	{ID: "m2", Lat: -6.1754, Long: 106.8272},
+	{ID: "m3", Lat: -6.2297, Long: 106.8295},
+	{ID: "m4", Lat: -6.1862, Long: 106.7980},
+	{ID: "m5", Lat: -6.2146, Long: 106.8451},
+	{ID: "m6", Lat: -6.1944, Long: 106.8229},
+	{ID: "m7", Lat: -6.2415, Long: 106.7992},
+	{ID: "m8", Lat: -6.1689, Long: 106.8065},
+}
+
+var (
+	testStart = Point{ID: "starting-merchant", Lat: -6.2088, Long: 106.8456}
+	testUser  = Point{ID: "user", Lat: -6.1900, Long: 106.7600}
+)
+
+// Shortest distance found by trying every visiting order of the stops
+func bruteForceDistance(start Point, stops Vertex, end Point) float64 {
+	shortest := math.Inf(1)
+
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(stops) {
+			ordered := append(append(Vertex{start}, stops...), end)
+			if d := NewRoute(ordered).Distance; d < shortest {
+				shortest = d
+			}
+			return
+		}
+
+		for i := k; i < len(stops); i++ {
+			stops[k], stops[i] = stops[i], stops[k]
+			permute(k + 1)
+			stops[k], stops[i] = stops[i], stops[k]
+		}
+	}
+	permute(0)
+
+	return shortest
+}
+
+// Route should start from the start point, end at the end point and visit every stop exactly once
+func assertValidRoute(t *testing.T, route Route, start Point, stops Vertex, end Point) {
+	t.Helper()
+
+	if len(route.Stops) != len(stops)+2 {
+		t.Fatalf("route has %d points, want %d", len(route.Stops), len(stops)+2)
+	}
+
+	if route.Stops[0].ID != start.ID {
+		t.Errorf("route starts from %s, want %s", route.Stops[0].ID, start.ID)
+	}
+
+	if last := route.Stops[len(route.Stops)-1]; last.ID != end.ID {
+		t.Errorf("route ends at %s, want %s", last.ID, end.ID)
+	}
+
+	visited := make(map[string]int)
+	for _, p := range route.Stops[1 : len(route.Stops)-1] {
+		visited[p.ID]++
+	}
+
+	for _, p := range stops {
+		if visited[p.ID] != 1 {
+			t.Errorf("stop %s is visited %d times, want 1", p.ID, visited[p.ID])
+		}
+	}
+
+	if len(route.Legs) != len(route.Stops)-1 {
+		t.Errorf("route has %d legs, want %d", len(route.Legs), len(route.Stops)-1)
+	}
+
+	var total float64
+	for _, leg := range route.Legs {
+		total += leg
+	}
+
+	if math.Abs(total-route.Distance) > 1e-9 {
+		t.Errorf("sum of legs is %f, route distance is %f", total, route.Distance)
+	}
+}
+
+func TestShortestRoute(t *testing.T) {
+	for n := 0; n <= len(testStops); n++ {
+		stops := append(Vertex{}, testStops[:n]...)
+
+		t.Run(fmt.Sprintf("%d stops", n), func(t *testing.T) {
+			route, err := ShortestRoute(testStart, stops, testUser)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertValidRoute(t, route, testStart, stops, testUser)
+
+			if !route.Optimal {
+				t.Error("exact route should be optimal")
+			}
+
+			want := bruteForceDistance(testStart, stops, testUser)
+			if math.Abs(route.Distance-want) > 1e-9 {
+				t.Errorf("distance is %f, brute force is %f", route.Distance, want)
+			}
+		})
+	}
+}
+
+func TestShortestRouteTooManyStops(t *testing.T) {
+	stops := make(Vertex, MaxExactStops+1)
+	for i := range stops {
+		stops[i] = Point{ID: fmt.Sprintf("m%d", i), Lat: -6.2 + float64(i)*0.001, Long: 106.8}
+	}
+
+	if _, err := ShortestRoute(testStart, stops, testUser); err != ErrTooManyStops {
+		t.Errorf("error is %v, want %v", err, ErrTooManyStops)
+	}
+}
+
+func TestSolveRoute(t *testing.T) {
+	tests := []struct {
+		name          string
+		stops         int
+		exactMaxStops int
+		wantOptimal   bool
+	}{
+		{name: "exact without stops", stops: 0, exactMaxStops: 3, wantOptimal: true},
+		{name: "exact below the limit", stops: 2, exactMaxStops: 3, wantOptimal: true},
+		{name: "exact at the limit", stops: 3, exactMaxStops: 3, wantOptimal: true},
+		{name: "heuristic above the limit", stops: 4, exactMaxStops: 3, wantOptimal: false},
+		{name: "heuristic with many stops", stops: 8, exactMaxStops: 5, wantOptimal: false},
+		{name: "limit is capped by MaxExactStops", stops: 8, exactMaxStops: MaxExactStops + 10, wantOptimal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stops := append(Vertex{}, testStops[:tt.stops]...)
+
+			opts := RouteOptions{ExactMaxStops: tt.exactMaxStops, Deadline: time.Second}
+
+			route := SolveRoute(testStart, stops, testUser, opts)
+			assertValidRoute(t, route, testStart, stops, testUser)
+
+			if route.Optimal != tt.wantOptimal {
+				t.Errorf("optimal is %t, want %t", route.Optimal, tt.wantOptimal)
+			}
+
+			want := bruteForceDistance(testStart, stops, testUser)
+
+			if route.Distance < want-1e-9 {
+				t.Errorf("distance %f is shorter than brute force %f", route.Distance, want)
+			}
+
+			// Exact route must be the shortest, heuristic route may be a bit longer
+			tolerance := 1e-9
+			if !route.Optimal {
+				tolerance = want * 0.05
+			}
+
+			if route.Distance-want > tolerance {
+				t.Errorf("distance is %f, brute force is %f", route.Distance, want)
+			}
+		})
+	}
+}
+
+func TestSolveRouteBeyondMaxExactStops(t *testing.T) {
+	stops := make(Vertex, MaxExactStops+1)
+	for i := range stops {
+		angle := float64(i) * 2 * math.Pi / float64(len(stops))
+		stops[i] = Point{
+			ID:   fmt.Sprintf("m%d", i),
+			Lat:  -6.2 + 0.02*math.Sin(angle),
+			Long: 106.8 + 0.02*math.Cos(angle),
+		}
+	}
+
+	route := SolveRoute(testStart, stops, testUser, RouteOptions{})
+	assertValidRoute(t, route, testStart, stops, testUser)
+
+	if route.Optimal {
+		t.Error("route above MaxExactStops should be solved by heuristic solver")
+	}
+}
+
+func TestHeuristicRouteDeadline(t *testing.T) {
+	stops := make(Vertex, 400)
+	for i := range stops {
+		// Deterministic scatter in a 10 km box
+		stops[i] = Point{
+			ID:   fmt.Sprintf("m%d", i),
+			Lat:  -6.25 + math.Mod(float64(i)*0.0371, 0.09),
+			Long: 106.75 + math.Mod(float64(i)*0.0577, 0.09),
+		}
+	}
+
+	t.Run("expired deadline returns nearest neighbour route", func(t *testing.T) {
+		route := HeuristicRoute(testStart, stops, testUser, time.Now().Add(-time.Second))
+		assertValidRoute(t, route, testStart, stops, testUser)
+
+		nodes := append(append(Vertex{testStart}, stops...), testUser)
+		nearest := nearestNeighbourTour(distanceMatrix(nodes), len(stops))
+
+		for i, node := range nearest {
+			if route.Stops[i].ID != nodes[node].ID {
+				t.Fatalf("stop %d is %s, want nearest neighbour %s", i, route.Stops[i].ID, nodes[node].ID)
+			}
+		}
+	})
+
+	t.Run("solver stops at the deadline", func(t *testing.T) {
+		deadline := 20 * time.Millisecond
+
+		started := time.Now()
+		route := SolveRoute(testStart, stops, testUser, RouteOptions{Deadline: deadline})
+		elapsed := time.Since(started)
+
+		assertValidRoute(t, route, testStart, stops, testUser)
+
+		// Distance matrix is built before the deadline starts, so allow some slack
+		if elapsed > deadline+500*time.Millisecond {
+			t.Errorf("solver took %s with deadline %s", elapsed, deadline)
+		}
+	})
+}
@@ -90,6 +90,32 @@ func ErrConflict(message string, err error) *GlobalError {
 	return baseError
 }
 
+// Return gone error structure with customize message and error.
+func ErrGone(message string, err error) *GlobalError {
+	if err != nil {
+		logger.Info(err.Error())
+	} else {
+		logger.Info(message)
+	}
+
+	baseError := ErrBase(http.StatusGone, message, err)
+
+	return baseError
+}
+
+// Return unprocessable entity error structure with customize message and error.
+func ErrUnprocessableEntity(message string, err error) *GlobalError {
+	if err != nil {
+		logger.Info(err.Error())
+	} else {
+		logger.Info(message)
+	}
+
+	baseError := ErrBase(http.StatusUnprocessableEntity, message, err)
+
+	return baseError
+}
+
 // Return bad request error structure with customize message and error.
 func ErrBadRequest(message interface{}, err error) *GlobalError {
 	if err != nil {
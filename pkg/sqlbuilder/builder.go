@@ -0,0 +1,175 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// Default limit of paginated query
+const DefaultLimit = 5
+
+// Return the direction of the given value (asc / desc, case insensitive),
+// otherwise fallback direction is returned
+func ParseDirection(value string, fallback Direction) Direction {
+	switch strings.ToUpper(value) {
+	case string(Asc):
+		return Asc
+	case string(Desc):
+		return Desc
+	}
+
+	return fallback
+}
+
+// Normalize limit & offset. Limit that is not positive uses DefaultLimit
+// and negative offset is treated as 0.
+func Page(limit int, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// Escape LIKE wildcard of the value and wrap it with %,
+// so it can be used to search value that contains the given text
+func Contains(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	return "%" + replacer.Replace(value) + "%"
+}
+
+// Builder generate SQL query with positional placeholder ($1, $2, ...).
+// User input is always passed as argument, never written into the query.
+type Builder struct {
+	base       string
+	where      []string
+	orderBy    []string
+	limit      string
+	args       []any
+	filterArgs int // Arguments used by base query and conditions
+}
+
+// Create new builder from base query that doesn't have WHERE clause.
+// args is the argument of "?" placeholder in the base query.
+func New(base string, args ...any) *Builder {
+	b := &Builder{}
+	b.base = b.bind(base, args)
+	b.filterArgs = len(b.args)
+
+	return b
+}
+
+// Add argument and return its placeholder
+func (b *Builder) Arg(value any) string {
+	b.args = append(b.args, value)
+
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// Replace every "?" in the SQL with the placeholder of its argument
+func (b *Builder) bind(sql string, args []any) string {
+	parts := strings.Split(sql, "?")
+	if len(parts)-1 != len(args) {
+		panic(fmt.Sprintf("sqlbuilder: %d placeholder with %d argument in %q", len(parts)-1, len(args), sql))
+	}
+
+	var result strings.Builder
+	for i, part := range parts {
+		result.WriteString(part)
+
+		if i < len(args) {
+			result.WriteString(b.Arg(args[i]))
+		}
+	}
+
+	return result.String()
+}
+
+// Add condition joined with AND, "?" in the condition is replaced with the argument placeholder
+func (b *Builder) Where(condition string, args ...any) *Builder {
+	b.where = append(b.where, b.bind(condition, args))
+	b.filterArgs = len(b.args)
+
+	return b
+}
+
+// Add order by column. Column must come from the code, never from user input.
+func (b *Builder) OrderBy(column string, direction Direction) *Builder {
+	b.orderBy = append(b.orderBy, fmt.Sprintf("%s %s", column, direction))
+
+	return b
+}
+
+// Add order by column of the given sort key.
+// Only key listed in the allowed columns (sort key => SQL column) is used,
+// and false is returned if the key is not allowed.
+func (b *Builder) SortBy(allowed map[string]string, key string, direction Direction) bool {
+	column, exists := allowed[key]
+	if !exists {
+		return false
+	}
+
+	b.OrderBy(column, direction)
+
+	return true
+}
+
+// Add normalized limit & offset, see Page
+func (b *Builder) Paginate(limit int, offset int) *Builder {
+	limit, offset = Page(limit, offset)
+
+	b.limit = fmt.Sprintf(" LIMIT %s OFFSET %s", b.Arg(limit), b.Arg(offset))
+
+	return b
+}
+
+// Base query with its conditions
+func (b *Builder) filter() string {
+	query := b.base
+
+	if len(b.where) > 0 {
+		query += " WHERE " + strings.Join(b.where, " AND ")
+	}
+
+	return query
+}
+
+// Generated query
+func (b *Builder) String() string {
+	query := b.filter()
+
+	if len(b.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(b.orderBy, ", ")
+	}
+
+	return query + b.limit
+}
+
+// Arguments of the placeholders in the generated query
+func (b *Builder) Args() []any {
+	return b.args
+}
+
+// Generated query and its arguments
+func (b *Builder) Build() (string, []any) {
+	return b.String(), b.Args()
+}
+
+// Query to count every row that matches the conditions, without order and pagination.
+// Only argument added before the last condition is used, so argument for
+// the order or the outer query should be added after every condition.
+func (b *Builder) Count() (string, []any) {
+	return fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS counted", b.filter()), b.args[:b.filterArgs]
+}
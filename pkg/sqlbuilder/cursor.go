@@ -0,0 +1,60 @@
+package sqlbuilder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("cursor is not valid")
+
+// Position of the last row of a page ordered by created at and ID
+type Cursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+// Encode the cursor into an opaque URL safe token
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode cursor token generated by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := Cursor{}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == "" || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// Add condition to get rows after the cursor.
+// The query should be ordered by the created at and ID column with the same direction.
+func (b *Builder) After(cursor *Cursor, createdAtColumn string, idColumn string, direction Direction) *Builder {
+	operator := ">"
+	if direction == Desc {
+		operator = "<"
+	}
+
+	return b.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", createdAtColumn, idColumn, operator), cursor.CreatedAt, cursor.ID)
+}
+
+// Token of the next page cursor, empty if the page is not full which means there is no next page
+func NextCursor(rows int, limit int, last Cursor) string {
+	limit, _ = Page(limit, 0)
+	if rows < limit {
+		return ""
+	}
+
+	return last.Encode()
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
 	"belimang/internal/merchant"
+	"belimang/internal/promotion"
 	"belimang/internal/purchase"
+	"belimang/internal/review"
 	"belimang/internal/user"
 	"belimang/internal/image"
 	"belimang/pkg/response"
@@ -12,7 +15,9 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func NewRoute(engine *gin.Engine, db *sqlx.DB) {
+// NewRoute register every route to the engine.
+// Background job is stopped when the context is cancelled on shutdown.
+func NewRoute(ctx context.Context, engine *gin.Engine, db *sqlx.DB) {
 	// Handle for not found routes
 	engine.NoRoute(NoRouteHandler)
 	router := engine.Group("")
@@ -21,14 +26,17 @@ func NewRoute(engine *gin.Engine, db *sqlx.DB) {
 
 	initializeMerchantHandler(db, router)
 	initializeUserHandler(db, router)
-	initializeOrderHandler(db, router)
+	initializeOrderHandler(ctx, db, router)
+	initializePromotionHandler(db, router)
+	initializeReviewHandler(db, router)
 	initializeImageHandler(router)
 }
 
 func initializeMerchantHandler(db *sqlx.DB, router *gin.RouterGroup) {
 	// Initialize all necessary dependecies
 	merchantRepo := merchant.NewMerchantRepository(db)
-	merchantUc := merchant.NewMerchantUsecase(merchantRepo)
+	scheduleRepo := merchant.NewScheduleRepository(db)
+	merchantUc := merchant.NewMerchantUsecase(merchantRepo, scheduleRepo)
 	merchantH := merchant.NewMerchantHandler(merchantUc)
 
 	merchantH.Router(router)
@@ -43,17 +51,49 @@ func initializeUserHandler(db *sqlx.DB, router *gin.RouterGroup) {
 	userH.Router(router)
 }
 
-func initializeOrderHandler(db *sqlx.DB, router *gin.RouterGroup) {
+func initializeOrderHandler(ctx context.Context, db *sqlx.DB, router *gin.RouterGroup) {
 	merchantRepo := merchant.NewMerchantRepository(db)
-	merchantUc := merchant.NewMerchantUsecase(merchantRepo)
+	scheduleRepo := merchant.NewScheduleRepository(db)
+	merchantUc := merchant.NewMerchantUsecase(merchantRepo, scheduleRepo)
+
+	promotionRepo := promotion.NewPromotionRepository(db)
+	promotionUc := promotion.NewPromotionUsecase(promotionRepo, merchantUc)
 
 	orderRepo := purchase.NewOrderRepository(db)
-	orderUc := purchase.NewOrderUsecase(orderRepo, merchantUc)
+	idempotencyRepo := purchase.NewIdempotencyRepository(db)
+	orderUc := purchase.NewOrderUsecase(orderRepo, idempotencyRepo, merchantUc, purchase.NewEtaModel(purchase.LoadEtaConfig()), purchase.NewPricingEngine(purchase.LoadPricingConfig()), promotionUc)
 	orderH := purchase.NewOrderHandler(orderUc)
 
+	// Give back stock of estimation that is never placed
+	go purchase.RunStockReleaser(ctx, orderUc)
+
 	orderH.Router(router)
 }
 
+func initializePromotionHandler(db *sqlx.DB, router *gin.RouterGroup) {
+	merchantRepo := merchant.NewMerchantRepository(db)
+	scheduleRepo := merchant.NewScheduleRepository(db)
+	merchantUc := merchant.NewMerchantUsecase(merchantRepo, scheduleRepo)
+
+	promotionRepo := promotion.NewPromotionRepository(db)
+	promotionUc := promotion.NewPromotionUsecase(promotionRepo, merchantUc)
+	promotionH := promotion.NewPromotionHandler(promotionUc)
+
+	promotionH.Router(router)
+}
+
+func initializeReviewHandler(db *sqlx.DB, router *gin.RouterGroup) {
+	merchantRepo := merchant.NewMerchantRepository(db)
+	scheduleRepo := merchant.NewScheduleRepository(db)
+	merchantUc := merchant.NewMerchantUsecase(merchantRepo, scheduleRepo)
+
+	reviewRepo := review.NewReviewRepository(db)
+	reviewUc := review.NewReviewUsecase(reviewRepo, merchantUc)
+	reviewH := review.NewReviewHandler(reviewUc)
+
+	reviewH.Router(router)
+}
+
 func initializeImageHandler(router *gin.RouterGroup) {
 	imageH := image.NewImageHandler()
 